@@ -19,7 +19,7 @@ import (
 const OCI_PLUGIN_ID = "oci-logs-datasource"
 
 func wrappedNewOCIDatasource(ctx context.Context, settings backend.DataSourceInstanceSettings) (instancemgmt.Instance, error) {
-	return plugin.NewOCIDatasource(settings) // forward to original function
+	return plugin.NewOCIDatasource(ctx, settings) // forward to original function
 }
 
 func main() {
@@ -0,0 +1,154 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+
+	"github.com/oracle/oci-grafana-logs/pkg/plugin/constants"
+	"github.com/oracle/oci-grafana-logs/pkg/plugin/models"
+)
+
+// Names of the fields of an annotation frame as expected by Grafana
+const (
+	annotationField_Time    = "time"
+	annotationField_TimeEnd = "timeEnd"
+	annotationField_Title   = "title"
+	annotationField_Text    = "text"
+	annotationField_Tags    = "tags"
+)
+
+// processAnnotations runs the log search of an annotation query and maps the returned log records
+// to annotation fields: time, optional timeEnd, title, text and tags, the tags being a string array.
+// Records without a valid time are skipped, and records with the same time and text are reported only once.
+//
+// Parameters:
+//   - ctx: The context for the request execution.
+//   - query: The backend DataQuery containing the request details.
+//   - queryModel: The query model with the search query and the annotation mapping.
+//   - fromMs: The start time in milliseconds since Unix epoch.
+//   - toMs: The end time in milliseconds since Unix epoch.
+//   - mFieldDefns: A map to store the annotation field definitions.
+//   - takey: The tenancy key for accessing the appropriate OCI client.
+//
+// Returns:
+//   - The annotation field definitions.
+//   - An error if the log search operation fails.
+func (o *OCIDatasource) processAnnotations(ctx context.Context,
+	query backend.DataQuery, queryModel *models.QueryModel, fromMs int64, toMs int64, mFieldDefns map[string]*DataFieldElements, takey string) (map[string]*DataFieldElements, error) {
+
+	mapping := queryModel.Annotation
+	if mapping.TimeField == "" {
+		mapping.TimeField = constants.DefaultAnnotationTimeField
+	}
+	if mapping.TextField == "" {
+		mapping.TextField = constants.DefaultAnnotationTextField
+	}
+	limit := mapping.Limit
+	if limit <= 0 {
+		limit = constants.DefaultAnnotationLimit
+	} else if limit > constants.MaxAnnotationLimit {
+		limit = constants.MaxAnnotationLimit
+	}
+
+	o.logger.Debug("Processing annotation search query", "refId", query.RefID, "query", queryModel.QueryText,
+		"from", query.TimeRange.From, "to", query.TimeRange.To, "limit", limit)
+
+	logRecords, err := o.searchLogRecords(ctx, takey, queryModel.QueryText, time.UnixMilli(fromMs), time.UnixMilli(toMs), limit)
+	if err != nil {
+		o.logger.Error("Annotation log search operation FAILED", "refId", query.RefID, "error", err)
+		return nil, err
+	}
+
+	timeFieldDefn := o.getCreateDataFieldElemsForField(mFieldDefns, len(logRecords),
+		annotationField_Time, annotationField_Time, FieldValueType(constants.ValueType_Time))
+	textFieldDefn := o.getCreateDataFieldElemsForField(mFieldDefns, len(logRecords),
+		annotationField_Text, annotationField_Text, FieldValueType(constants.ValueType_String))
+	var timeEndFieldDefn, titleFieldDefn, tagsFieldDefn *DataFieldElements
+	if mapping.TimeEndField != "" {
+		timeEndFieldDefn = o.getCreateDataFieldElemsForField(mFieldDefns, len(logRecords),
+			annotationField_TimeEnd, annotationField_TimeEnd, FieldValueType(constants.ValueType_Time))
+	}
+	if mapping.TitleField != "" {
+		titleFieldDefn = o.getCreateDataFieldElemsForField(mFieldDefns, len(logRecords),
+			annotationField_Title, annotationField_Title, FieldValueType(constants.ValueType_String))
+	}
+	if mapping.TagsField != "" {
+		tagsFieldDefn = o.getCreateDataFieldElemsForField(mFieldDefns, len(logRecords),
+			annotationField_Tags, annotationField_Tags, FieldValueType(constants.ValueType_JSON))
+	}
+
+	seen := make(map[string]struct{})
+	numAnnotations := 0
+	for rowCount, logRecord := range logRecords {
+		timeValue, _ := lookupLogField(logRecord, mapping.TimeField)
+		timestamp, err := parseLogTime(timeValue)
+		if err != nil {
+			o.logger.Debug("Skipping log record without a valid annotation time", "refId", query.RefID,
+				"row", rowCount, "field", mapping.TimeField, "error", err)
+			continue
+		}
+
+		textValue, _ := lookupLogField(logRecord, mapping.TextField)
+		text := logFieldString(textValue)
+
+		// Deduplicate annotations sharing the same time and text
+		dedupKey := strconv.FormatInt(timestamp.UnixMilli(), 10) + "|" + text
+		if _, ok := seen[dedupKey]; ok {
+			continue
+		}
+		seen[dedupKey] = struct{}{}
+
+		timeFieldDefn.Values.([]*time.Time)[numAnnotations] = &timestamp
+		textFieldDefn.Values.([]*string)[numAnnotations] = &text
+
+		if timeEndFieldDefn != nil {
+			if timeEndValue, ok := lookupLogField(logRecord, mapping.TimeEndField); ok {
+				if timeEnd, err := parseLogTime(timeEndValue); err == nil {
+					timeEndFieldDefn.Values.([]*time.Time)[numAnnotations] = &timeEnd
+				}
+			}
+		}
+		if titleFieldDefn != nil {
+			titleValue, _ := lookupLogField(logRecord, mapping.TitleField)
+			title := logFieldString(titleValue)
+			titleFieldDefn.Values.([]*string)[numAnnotations] = &title
+		}
+		if tagsFieldDefn != nil {
+			tags := make([]string, 0)
+			for _, tagField := range strings.Split(mapping.TagsField, ",") {
+				if tagValue, ok := lookupLogField(logRecord, strings.TrimSpace(tagField)); ok && tagValue != nil {
+					tags = append(tags, logFieldString(tagValue))
+				}
+			}
+			// A string array, as a comma separated string would split the tags containing a comma
+			tagsJSON, _ := json.Marshal(tags)
+			tagsRaw := json.RawMessage(tagsJSON)
+			tagsFieldDefn.Values.([]*json.RawMessage)[numAnnotations] = &tagsRaw
+		}
+		numAnnotations++
+	}
+
+	// Drop the entries left unused by the skipped and duplicated log records
+	for _, fieldDefn := range mFieldDefns {
+		switch values := fieldDefn.Values.(type) {
+		case []*time.Time:
+			fieldDefn.Values = values[:numAnnotations]
+		case []*string:
+			fieldDefn.Values = values[:numAnnotations]
+		case []*json.RawMessage:
+			fieldDefn.Values = values[:numAnnotations]
+		}
+	}
+
+	return mFieldDefns, nil
+}
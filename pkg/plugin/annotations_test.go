@@ -0,0 +1,220 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/oracle/oci-go-sdk/v65/loggingsearch"
+
+	"github.com/oracle/oci-grafana-logs/pkg/plugin/models"
+)
+
+// newLogSearchDatasource creates a datasource whose single tenancy searches the given log records,
+// returning at most the limit of each search request. The limits of the requests are recorded in limits.
+func newLogSearchDatasource(t *testing.T, records []map[string]interface{}, limits *[]int) *OCIDatasource {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		limit, _ := strconv.Atoi(req.URL.Query().Get("limit"))
+		*limits = append(*limits, limit)
+		results := make([]map[string]interface{}, 0, len(records))
+		for _, record := range records {
+			if len(results) == limit {
+				break
+			}
+			results = append(results, map[string]interface{}{"data": map[string]interface{}{"logContent": record}})
+		}
+		content, _ := json.Marshal(map[string]interface{}{"results": results, "summary": map[string]interface{}{"resultCount": len(results)}})
+		_, _ = rw.Write(content)
+	}))
+	t.Cleanup(server.Close)
+
+	provider := common.NewRawConfigurationProvider("ocid1.tenancy.oc1..a", "ocid1.user.oc1..a", "us-ashburn-1",
+		testKeyFingerprint, string(readTestKey(t, "pkcs1.pem")), nil)
+	client, err := loggingsearch.NewLogSearchClientWithConfigurationProvider(provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Host = server.URL
+	return &OCIDatasource{
+		logger:        log.DefaultLogger,
+		tenancyAccess: map[string]*logTenancyAccess{SingleTenancyKey: {loggingSearchClient: client, config: provider}},
+		settings:      &models.OCIDatasourceSettings{},
+	}
+}
+
+// annotationValues returns the values of an annotation field as strings, "<nil>" for the missing values.
+func annotationValues(fieldDefn *DataFieldElements) []string {
+	if fieldDefn == nil {
+		return nil
+	}
+	values := make([]string, 0)
+	switch v := fieldDefn.Values.(type) {
+	case []*time.Time:
+		for _, value := range v {
+			if value == nil {
+				values = append(values, "<nil>")
+			} else {
+				values = append(values, value.UTC().Format(time.RFC3339))
+			}
+		}
+	case []*string:
+		for _, value := range v {
+			if value == nil {
+				values = append(values, "<nil>")
+			} else {
+				values = append(values, *value)
+			}
+		}
+	case []*json.RawMessage:
+		for _, value := range v {
+			if value == nil {
+				values = append(values, "<nil>")
+			} else {
+				values = append(values, string(*value))
+			}
+		}
+	}
+	return values
+}
+
+func TestProcessAnnotations(t *testing.T) {
+	record := func(time string, message string, extra map[string]interface{}) map[string]interface{} {
+		data := map[string]interface{}{"message": message}
+		for key, value := range extra {
+			data[key] = value
+		}
+		return map[string]interface{}{"time": time, "data": data}
+	}
+
+	tests := []struct {
+		name       string
+		mapping    models.AnnotationModel
+		records    []map[string]interface{}
+		wantLimits []int
+		want       map[string][]string
+	}{
+		{
+			name: "default time and text fields",
+			records: []map[string]interface{}{
+				record("2024-05-01T10:00:00Z", "deployed", nil),
+				record("2024-05-01T11:00:00Z", "rolled back", nil),
+			},
+			wantLimits: []int{100},
+			want: map[string][]string{
+				annotationField_Time: {"2024-05-01T10:00:00Z", "2024-05-01T11:00:00Z"},
+				annotationField_Text: {"deployed", "rolled back"},
+			},
+		},
+		{
+			name: "mapped fields",
+			mapping: models.AnnotationModel{TimeField: "data.start", TimeEndField: "data.end", TitleField: "data.event",
+				TextField: "data.detail", TagsField: "data.type, data.region, data.missing"},
+			records: []map[string]interface{}{
+				record("2024-05-01T09:00:00Z", "", map[string]interface{}{"start": "2024-05-01T10:00:00Z", "end": "2024-05-01T10:30:00Z",
+					"event": "UpdateInstance", "detail": "shape changed", "type": "audit", "region": "us, east"}),
+				record("2024-05-01T09:00:00Z", "", map[string]interface{}{"start": "2024-05-01T11:00:00Z", "end": "not a time",
+					"event": "StopInstance", "detail": "stopped", "type": "audit"}),
+			},
+			wantLimits: []int{100},
+			want: map[string][]string{
+				annotationField_Time:    {"2024-05-01T10:00:00Z", "2024-05-01T11:00:00Z"},
+				annotationField_TimeEnd: {"2024-05-01T10:30:00Z", "<nil>"},
+				annotationField_Title:   {"UpdateInstance", "StopInstance"},
+				annotationField_Text:    {"shape changed", "stopped"},
+				annotationField_Tags:    {`["audit","us, east"]`, `["audit"]`},
+			},
+		},
+		{
+			name: "records without a valid time are skipped",
+			records: []map[string]interface{}{
+				record("yesterday", "skipped", nil),
+				{"data": map[string]interface{}{"message": "no time"}},
+				record("2024-05-01T10:00:00Z", "kept", nil),
+			},
+			wantLimits: []int{100},
+			want: map[string][]string{
+				annotationField_Time: {"2024-05-01T10:00:00Z"},
+				annotationField_Text: {"kept"},
+			},
+		},
+		{
+			name:    "records with the same time and text are reported once",
+			mapping: models.AnnotationModel{TagsField: "data.type"},
+			records: []map[string]interface{}{
+				record("2024-05-01T10:00:00Z", "deployed", map[string]interface{}{"type": "first"}),
+				record("2024-05-01T10:00:00Z", "deployed", map[string]interface{}{"type": "duplicate"}),
+				record("2024-05-01T10:00:00Z", "rolled back", map[string]interface{}{"type": "same time"}),
+				record("2024-05-01T11:00:00Z", "deployed", map[string]interface{}{"type": "same text"}),
+			},
+			wantLimits: []int{100},
+			want: map[string][]string{
+				annotationField_Time: {"2024-05-01T10:00:00Z", "2024-05-01T10:00:00Z", "2024-05-01T11:00:00Z"},
+				annotationField_Text: {"deployed", "rolled back", "deployed"},
+				annotationField_Tags: {`["first"]`, `["same time"]`, `["same text"]`},
+			},
+		},
+		{
+			name:    "rows capped by the limit",
+			mapping: models.AnnotationModel{Limit: 2},
+			records: []map[string]interface{}{
+				record("2024-05-01T10:00:00Z", "a", nil),
+				record("2024-05-01T11:00:00Z", "b", nil),
+				record("2024-05-01T12:00:00Z", "c", nil),
+			},
+			wantLimits: []int{2},
+			want: map[string][]string{
+				annotationField_Time: {"2024-05-01T10:00:00Z", "2024-05-01T11:00:00Z"},
+				annotationField_Text: {"a", "b"},
+			},
+		},
+		{
+			name:       "limit capped by the maximum",
+			mapping:    models.AnnotationModel{Limit: 5000},
+			records:    []map[string]interface{}{record("2024-05-01T10:00:00Z", "a", nil)},
+			wantLimits: []int{1000},
+			want: map[string][]string{
+				annotationField_Time: {"2024-05-01T10:00:00Z"},
+				annotationField_Text: {"a"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var limits []int
+			o := newLogSearchDatasource(t, tt.records, &limits)
+			from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+			query := backend.DataQuery{RefID: "A", TimeRange: backend.TimeRange{From: from, To: from.Add(24 * time.Hour)}}
+
+			fields, err := o.processAnnotations(context.Background(), query, &models.QueryModel{QueryText: `search "ocid1.compartment.oc1..a"`, Annotation: tt.mapping},
+				query.TimeRange.From.UnixMilli(), query.TimeRange.To.UnixMilli(), make(map[string]*DataFieldElements), SingleTenancyKey)
+			if err != nil {
+				t.Fatalf("processAnnotations() error = %v", err)
+			}
+			if !reflect.DeepEqual(limits, tt.wantLimits) {
+				t.Fatalf("search limits = %v, want %v", limits, tt.wantLimits)
+			}
+			got := make(map[string][]string, len(fields))
+			for key, fieldDefn := range fields {
+				got[key] = annotationValues(fieldDefn)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("processAnnotations() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -12,6 +12,21 @@ const (
 	ALL_REGION                     = "all-subscribed-region"
 	FETCH_FOR_NAMESPACE            = "namespace"
 )
+
+// Supported values of the datasource environment setting
+const Environment_Local = "local"
+const Environment_Instance = "OCI Instance"
+const Environment_ResourcePrincipal = "OCI Resource Principal"
+const Environment_OkeWorkloadIdentity = "OKE Workload Identity"
+const Environment_SessionToken = "OCI Session Token"
+const Environment_ConfigFile = "OCI Config File"
+
+// Defaults for the OCI CLI configuration file used by session token authentication
+const DefaultOCIConfigFilePath = "~/.oci/config"
+const DefaultOCIConfigProfile = "DEFAULT"
+const SessionTokenRefreshMargin = 300 // seconds before expiry at which the session token is refreshed
+const ConfigFileReloadInterval = 30   // seconds between two checks for changes of the OCI config and key files
+
 const MaxPagesToFetch = 20
 const SingleTenancyKey = "DEFAULT/"
 const NoTenancy = "NoTenancy"
@@ -33,6 +48,56 @@ const MaxLogMetricsDataPoints = 10
 const DefaultLogMetricsDataPoints = 5
 const MinLogMetricsDataPoints = 2
 
+// Constants for the gap filling modes of log metric time series queries
+const FillMode_None = ""
+const FillMode_Zero = "zero"
+const FillMode_Null = "null"
+const FillMode_Previous = "previous"
+
+// Constants for the sort direction of log records queries, records are returned newest first by default
+const SortDirection_Asc = "asc"
+const SortDirection_Desc = "desc"
+const DefaultSortDirection = SortDirection_Desc
+
+// Query type sent by Grafana for annotation queries
+const QueryType_Annotation = "annotation"
+
+// Defaults and limits for annotation queries
+const DefaultAnnotationTimeField = "time"
+const DefaultAnnotationTextField = "data.message"
+const DefaultAnnotationLimit = 100
+const MaxAnnotationLimit = 1000
+
+// Constants for the live tail of log records through Grafana Live
+const LiveTailPathPrefix = "tail/"
+const LiveTailPollInterval = 5 // seconds between two log searches
+const LiveTailWindow = 120     // seconds of logs searched by each poll, to catch late ingested records
+const LiveTailMaxRecordsPerPoll = 1000
+
+// Constants for the log volume histogram of log records queries
+const LogVolumeMaxBuckets = 120
+const LogVolumeLevelProbeRecords = 50
+const LogVolumeLevelFieldCacheTTL = 600 // seconds the level field detected for a query is reused
+
+// Constants for the log context of a log record
+const LogContextSearchWindow = 3600 // seconds searched before and after the log record
+const DefaultLogContextLines = 10
+const MaxLogContextLines = 100
+
+// Upper bound on the number of rounddown buckets generated when filling gaps
+const MaxFillDataPoints = 11000
+
+// Timeout of the HTTP client of the OCI clients when a proxy is configured, as the OCI SDK default dispatcher
+const HTTPClientTimeout = 60 // seconds
+
+// Constants for the time-range chunking of log records queries
+const DefaultSearchChunkSize = 1440 // minutes
+const MaxSearchChunks = 32
+const MaxParallelSearchChunks = 4
+
+// Upper bound on the timeout of a query, set per datasource or per query
+const MaxQueryTimeout = 600 // seconds
+
 type FieldValueType int
 
 const (
@@ -41,4 +106,5 @@ const (
 	ValueType_Int
 	ValueType_Time
 	ValueType_String
+	ValueType_JSON
 )
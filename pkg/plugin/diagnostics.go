@@ -0,0 +1,236 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/oracle/oci-go-sdk/v65/identity"
+	"github.com/oracle/oci-go-sdk/v65/logging"
+	"github.com/oracle/oci-go-sdk/v65/loggingsearch"
+	"github.com/pkg/errors"
+
+	"github.com/oracle/oci-grafana-logs/pkg/plugin/constants"
+	"github.com/oracle/oci-grafana-logs/pkg/plugin/models"
+)
+
+// Permissions needed by the plugin, as verbs and resource types of IAM policy statements.
+const (
+	permissionInspectTenancies    = "inspect tenancies"
+	permissionInspectCompartments = "inspect compartments"
+	permissionReadLogGroups       = "read log-groups"
+	permissionReadLogContent      = "read log-content"
+)
+
+// policySubject returns the subject of the IAM policy statements granting permissions to the principal
+// of the datasource, with placeholders for the names that the plugin cannot know.
+//
+// Returns:
+//   - string: The subject, e.g. "group <group-name>".
+//   - string: The condition of the statements, empty when the subject needs none.
+func (o *OCIDatasource) policySubject() (string, string) {
+	switch o.settings.Environment {
+	case constants.Environment_Instance, constants.Environment_ResourcePrincipal:
+		return "dynamicgroup <dynamic-group-name>", ""
+	case constants.Environment_OkeWorkloadIdentity:
+		return "any-user", " where all {request.principal.type = 'workload', request.principal.cluster_id = '<cluster-ocid>', " +
+			"request.principal.namespace = '<namespace>', request.principal.service_account = '<service-account>'}"
+	default:
+		return "group <group-name>", ""
+	}
+}
+
+// policyStatement builds the IAM policy statement granting a permission in a compartment,
+// or in the whole tenancy when no compartment is given.
+//
+// Parameters:
+//   - permission: The verb and resource type, e.g. "read log-content".
+//   - compartment: The OCID of the compartment, empty or the tenancy OCID for the whole tenancy.
+//   - tenancyocid: The OCID of the tenancy.
+//
+// Returns:
+//   - string: The policy statement.
+func (o *OCIDatasource) policyStatement(permission string, compartment string, tenancyocid string) string {
+	subject, condition := o.policySubject()
+	location := "tenancy"
+	if compartment != "" && compartment != tenancyocid {
+		location = "compartment id " + compartment
+	}
+	return fmt.Sprintf("allow %s to %s in %s%s", subject, permission, location, condition)
+}
+
+// diagnoseOperation records the result of an OCI operation probed by the diagnostics.
+//
+// Parameters:
+//   - operation: The name of the OCI operation.
+//   - permission: The permission the operation needs.
+//   - policy: The policy statement granting the permission.
+//   - err: The error returned by the operation, nil on success.
+//
+// Returns:
+//   - models.OperationDiagnostic: The result of the operation.
+func diagnoseOperation(operation string, permission string, policy string, err error) models.OperationDiagnostic {
+	diagnostic := models.OperationDiagnostic{Operation: operation, Permission: permission, Policy: policy, OK: err == nil}
+	if err == nil {
+		return diagnostic
+	}
+	diagnostic.Message = err.Error()
+	if serviceErr, ok := common.IsServiceError(err); ok {
+		diagnostic.StatusCode = serviceErr.GetHTTPStatusCode()
+		diagnostic.Code = serviceErr.GetCode()
+		diagnostic.Message = serviceErr.GetMessage()
+	}
+	return diagnostic
+}
+
+// RunDiagnostics probes the OCI operations used by the plugin for a tenancy, a compartment and a log group:
+// the region subscriptions of the tenancy, the listing of the log groups of the compartment, the listing of
+// the logs of the log group and a log search. For each failing operation, the IAM policy statement that
+// grants it is suggested.
+//
+// OCI answers 404 NotAuthorizedOrNotFound both when a resource does not exist and when it is not permitted,
+// so a failing operation on an existing resource usually means a missing policy.
+//
+// Parameters:
+//   - ctx: The context of the request.
+//   - rr: The tenancy, and optionally the compartment and the log group, to probe.
+//
+// Returns:
+//   - *models.DiagnosticsResult: The result of each operation and the suggested policy statements.
+//   - error: An error if the tenancy is invalid.
+func (o *OCIDatasource) RunDiagnostics(ctx context.Context, rr diagnosticsRequest) (*models.DiagnosticsResult, error) {
+	takey := o.GetTenancyAccessKey(rr.Tenancy)
+	if len(takey) == 0 {
+		return nil, errors.New("invalid tenancy: " + rr.Tenancy)
+	}
+	tenancyocid, err := o.FetchTenancyOCID(takey)
+	if err != nil {
+		return nil, err
+	}
+	ta := o.tenancyAccess[takey]
+
+	// The log search is scoped to the compartment of the log group, read from the log group when not given
+	compartment := rr.Compartment
+	if compartment == "" && rr.LogGroup != "" {
+		if logGroup, err := ta.loggingManagementClient.GetLogGroup(ctx, logging.GetLogGroupRequest{LogGroupId: common.String(rr.LogGroup)}); err == nil && logGroup.CompartmentId != nil {
+			compartment = *logGroup.CompartmentId
+		}
+	}
+	if compartment == "" {
+		compartment = tenancyocid
+	}
+	subject, _ := o.policySubject()
+	result := &models.DiagnosticsResult{Tenancy: takey, TenancyOCID: tenancyocid, Compartment: compartment, LogGroup: rr.LogGroup, Principal: subject}
+
+	// Region subscriptions of the tenancy, used to list the regions in the query editor
+	_, err = ta.identityClient.ListRegionSubscriptions(ctx, identity.ListRegionSubscriptionsRequest{TenancyId: common.String(tenancyocid)})
+	result.Operations = append(result.Operations, diagnoseOperation("identity:ListRegionSubscriptions", permissionInspectTenancies,
+		o.policyStatement(permissionInspectTenancies, "", tenancyocid), err))
+
+	// Log groups of the compartment
+	logGroups, err := ta.loggingManagementClient.ListLogGroups(ctx, logging.ListLogGroupsRequest{CompartmentId: common.String(compartment), Limit: common.Int(1)})
+	result.Operations = append(result.Operations, diagnoseOperation("logging:ListLogGroups", permissionReadLogGroups,
+		o.policyStatement(permissionReadLogGroups, compartment, tenancyocid), err))
+
+	// Logs of the log group, or of the first log group of the compartment when none is given
+	logGroup := rr.LogGroup
+	if logGroup == "" && err == nil && len(logGroups.Items) > 0 {
+		logGroup = *logGroups.Items[0].Id
+	}
+	if logGroup != "" {
+		_, err = ta.loggingManagementClient.ListLogs(ctx, logging.ListLogsRequest{LogGroupId: common.String(logGroup), Limit: common.Int(1)})
+		result.Operations = append(result.Operations, diagnoseOperation("logging:ListLogs", permissionReadLogGroups,
+			o.policyStatement(permissionReadLogGroups, compartment, tenancyocid), err))
+	} else {
+		result.Operations = append(result.Operations, models.OperationDiagnostic{Operation: "logging:ListLogs", Permission: permissionReadLogGroups,
+			Skipped: true, Message: "no log group to list the logs of"})
+	}
+
+	// Search of the log content of the compartment, or of the log group
+	scope := compartment
+	if logGroup != "" {
+		scope = compartment + "/" + logGroup
+	}
+	end := time.Now().UTC().Truncate(time.Millisecond)
+	_, err = ta.loggingSearchClient.SearchLogs(ctx, loggingsearch.SearchLogsRequest{
+		SearchLogsDetails: loggingsearch.SearchLogsDetails{
+			SearchQuery:       common.String(`search "` + scope + `"`),
+			TimeStart:         &common.SDKTime{Time: end.Add(-5 * time.Minute)},
+			TimeEnd:           &common.SDKTime{Time: end},
+			IsReturnFieldInfo: common.Bool(false),
+		},
+		Limit: common.Int(1),
+	})
+	result.Operations = append(result.Operations, diagnoseOperation("loggingsearch:SearchLogs", permissionReadLogContent,
+		o.policyStatement(permissionReadLogContent, compartment, tenancyocid), err))
+
+	result.Failing, result.Policies = failingOperations(result.Operations)
+
+	if len(result.Policies) > 0 && o.settings.Environment == constants.Environment_Instance && len(o.settings.TargetTenancies()) > 0 {
+		result.Notes = append(result.Notes, "For a cross tenancy, the source tenancy must endorse the dynamic group, e.g. "+
+			"\"endorse dynamicgroup <dynamic-group-name> to read log-content in any-tenancy\", and the statements above must "+
+			"be written as admit statements in the target tenancy, e.g. \"admit dynamicgroup <dynamic-group-name> of tenancy <source-alias> to read log-content in tenancy\".")
+	}
+	if len(result.Policies) > 0 {
+		result.Notes = append(result.Notes, "OCI returns 404 NotAuthorizedOrNotFound both for missing policies and for "+
+			"resources that do not exist, check the compartment and log group OCIDs if the policies are in place.")
+	}
+	backend.Logger.Debug("plugin.diagnostics", "RunDiagnostics", "tenancy", takey, "failing", result.Failing)
+
+	return result, nil
+}
+
+// failingOperations returns the failing operations probed by the diagnostics, and the policy statements
+// granting them, each statement being suggested once even when several operations need it.
+//
+// Parameters:
+//   - operations: The result of each probed operation.
+//
+// Returns:
+//   - []string: The failing operations, skipped operations excluded.
+//   - []string: The policy statements granting the failing operations, in the order of the operations.
+func failingOperations(operations []models.OperationDiagnostic) ([]string, []string) {
+	var failing, policies []string
+	suggested := make(map[string]bool)
+	for _, operation := range operations {
+		if operation.OK || operation.Skipped {
+			continue
+		}
+		failing = append(failing, operation.Operation)
+		if !suggested[operation.Policy] {
+			suggested[operation.Policy] = true
+			policies = append(policies, operation.Policy)
+		}
+	}
+	return failing, policies
+}
+
+// permissionHint returns a hint naming the permission an operation needs when OCI rejected it
+// as not authenticated or not authorized, and an empty string otherwise.
+//
+// Parameters:
+//   - err: The error returned by the operation.
+//   - permission: The permission the operation needs.
+//
+// Returns:
+//   - string: The hint to append to the error message.
+func permissionHint(err error, permission string) string {
+	serviceErr, ok := common.IsServiceError(err)
+	if !ok {
+		return ""
+	}
+	switch serviceErr.GetHTTPStatusCode() {
+	case 401:
+		return ", the credentials of the tenancy were rejected"
+	case 403, 404:
+		return fmt.Sprintf(", check that the IAM policies allow to %s in the compartments of the query, the diagnostics of the datasource tell which permission is missing", permission)
+	}
+	return ""
+}
@@ -0,0 +1,173 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/oracle/oci-grafana-logs/pkg/plugin/constants"
+	"github.com/oracle/oci-grafana-logs/pkg/plugin/models"
+)
+
+// testServiceError is an OCI service error, as returned by the OCI SDK clients.
+type testServiceError struct {
+	statusCode int
+	code       string
+}
+
+func (e testServiceError) Error() string           { return e.code }
+func (e testServiceError) GetHTTPStatusCode() int  { return e.statusCode }
+func (e testServiceError) GetMessage() string      { return "message of " + e.code }
+func (e testServiceError) GetCode() string         { return e.code }
+func (e testServiceError) GetOpcRequestID() string { return "request" }
+
+func TestPolicyStatement(t *testing.T) {
+	const tenancy = "ocid1.tenancy.oc1..t"
+	const compartment = "ocid1.compartment.oc1..c"
+
+	tests := []struct {
+		name        string
+		environment string
+		compartment string
+		want        string
+	}{
+		{
+			name:        "user principal in the tenancy",
+			environment: constants.Environment_Local,
+			want:        "allow group <group-name> to read log-content in tenancy",
+		},
+		{
+			name:        "user principal in the root compartment",
+			environment: constants.Environment_Local,
+			compartment: tenancy,
+			want:        "allow group <group-name> to read log-content in tenancy",
+		},
+		{
+			name:        "user principal in a compartment",
+			environment: constants.Environment_Local,
+			compartment: compartment,
+			want:        "allow group <group-name> to read log-content in compartment id " + compartment,
+		},
+		{
+			name:        "OCI config file in a compartment",
+			environment: constants.Environment_ConfigFile,
+			compartment: compartment,
+			want:        "allow group <group-name> to read log-content in compartment id " + compartment,
+		},
+		{
+			name:        "instance principal",
+			environment: constants.Environment_Instance,
+			compartment: compartment,
+			want:        "allow dynamicgroup <dynamic-group-name> to read log-content in compartment id " + compartment,
+		},
+		{
+			name:        "resource principal",
+			environment: constants.Environment_ResourcePrincipal,
+			want:        "allow dynamicgroup <dynamic-group-name> to read log-content in tenancy",
+		},
+		{
+			name:        "OKE workload identity",
+			environment: constants.Environment_OkeWorkloadIdentity,
+			compartment: compartment,
+			want: "allow any-user to read log-content in compartment id " + compartment + " where all {request.principal.type = 'workload', " +
+				"request.principal.cluster_id = '<cluster-ocid>', request.principal.namespace = '<namespace>', " +
+				"request.principal.service_account = '<service-account>'}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &OCIDatasource{settings: &models.OCIDatasourceSettings{Environment: tt.environment}}
+			if got := o.policyStatement(permissionReadLogContent, tt.compartment, tenancy); got != tt.want {
+				t.Fatalf("policyStatement() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPermissionHint(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "not authenticated", err: testServiceError{401, "NotAuthenticated"}, want: ", the credentials of the tenancy were rejected"},
+		{name: "not authorized", err: testServiceError{403, "NotAuthorized"}, want: "allow to read log-groups"},
+		{name: "not authorized or not found", err: testServiceError{404, "NotAuthorizedOrNotFound"}, want: "allow to read log-groups"},
+		{name: "other service error", err: testServiceError{500, "InternalServerError"}, want: ""},
+		{name: "not a service error", err: errors.New("connection refused"), want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := permissionHint(tt.err, permissionReadLogGroups)
+			if (tt.want == "") != (got == "") || !strings.Contains(got, tt.want) {
+				t.Fatalf("permissionHint() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFailingOperations(t *testing.T) {
+	const logGroupsPolicy = "allow group <group-name> to read log-groups in tenancy"
+	const logContentPolicy = "allow group <group-name> to read log-content in tenancy"
+
+	tests := []struct {
+		name         string
+		operations   []models.OperationDiagnostic
+		wantFailing  []string
+		wantPolicies []string
+	}{
+		{
+			name: "all operations succeed",
+			operations: []models.OperationDiagnostic{
+				diagnoseOperation("logging:ListLogGroups", permissionReadLogGroups, logGroupsPolicy, nil),
+				diagnoseOperation("loggingsearch:SearchLogs", permissionReadLogContent, logContentPolicy, nil),
+			},
+		},
+		{
+			name: "operations needing the same policy",
+			operations: []models.OperationDiagnostic{
+				diagnoseOperation("logging:ListLogGroups", permissionReadLogGroups, logGroupsPolicy, testServiceError{404, "NotAuthorizedOrNotFound"}),
+				diagnoseOperation("logging:ListLogs", permissionReadLogGroups, logGroupsPolicy, testServiceError{404, "NotAuthorizedOrNotFound"}),
+				diagnoseOperation("loggingsearch:SearchLogs", permissionReadLogContent, logContentPolicy, testServiceError{404, "NotAuthorizedOrNotFound"}),
+			},
+			wantFailing:  []string{"logging:ListLogGroups", "logging:ListLogs", "loggingsearch:SearchLogs"},
+			wantPolicies: []string{logGroupsPolicy, logContentPolicy},
+		},
+		{
+			name: "skipped operations are not failing",
+			operations: []models.OperationDiagnostic{
+				diagnoseOperation("logging:ListLogGroups", permissionReadLogGroups, logGroupsPolicy, nil),
+				{Operation: "logging:ListLogs", Permission: permissionReadLogGroups, Skipped: true},
+				diagnoseOperation("loggingsearch:SearchLogs", permissionReadLogContent, logContentPolicy, testServiceError{404, "NotAuthorizedOrNotFound"}),
+			},
+			wantFailing:  []string{"loggingsearch:SearchLogs"},
+			wantPolicies: []string{logContentPolicy},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failing, policies := failingOperations(tt.operations)
+			if !reflect.DeepEqual(failing, tt.wantFailing) || !reflect.DeepEqual(policies, tt.wantPolicies) {
+				t.Fatalf("failingOperations() = %v, %v, want %v, %v", failing, policies, tt.wantFailing, tt.wantPolicies)
+			}
+		})
+	}
+}
+
+func TestDiagnoseOperation(t *testing.T) {
+	diagnostic := diagnoseOperation("logging:ListLogs", permissionReadLogGroups, "policy", testServiceError{404, "NotAuthorizedOrNotFound"})
+	want := models.OperationDiagnostic{Operation: "logging:ListLogs", Permission: permissionReadLogGroups, Policy: "policy",
+		StatusCode: 404, Code: "NotAuthorizedOrNotFound", Message: "message of NotAuthorizedOrNotFound"}
+	if !reflect.DeepEqual(diagnostic, want) {
+		t.Fatalf("diagnoseOperation() = %+v, want %+v", diagnostic, want)
+	}
+}
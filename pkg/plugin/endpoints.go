@@ -0,0 +1,135 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/oracle/oci-go-sdk/v65/logging"
+
+	"github.com/oracle/oci-grafana-logs/pkg/plugin/constants"
+	"github.com/oracle/oci-grafana-logs/pkg/plugin/models"
+)
+
+// Endpoint templates of the OCI services used by the plugin, as used by the OCI SDK clients.
+// {region} is the region identifier and {secondLevelDomain} the domain of the realm of the region,
+// e.g. oraclecloud.com for OC1, oraclegovcloud.com for OC2 and OC3, oraclegovcloud.uk for OC4.
+const (
+	endpointTemplateLoggingSearch     = "https://logging.{region}.oci.{secondLevelDomain}"
+	endpointTemplateLoggingManagement = "https://logging.{region}.oci.{secondLevelDomain}"
+	endpointTemplateIdentity          = "https://identity.{region}.oci.{secondLevelDomain}"
+)
+
+// serviceEndpoints holds the endpoints of the OCI services of a tenancy. An empty endpoint keeps
+// the endpoint resolved by the OCI SDK client from the region of its configuration provider.
+type serviceEndpoints struct {
+	loggingSearch     string
+	loggingManagement string
+	identity          string
+}
+
+// endpointSettings holds the settings the endpoints of the clients of a tenancy are resolved from, so
+// that the endpoints of another region are resolved the same way. The fields are exported so that
+// the settings are part of the hash of the settings of the tenancy, see settingsHash.
+type endpointSettings struct {
+	CustomDomain string                    // The realm domain of a dedicated region, empty for known regions
+	Overrides    []models.EndpointSettings // The endpoint overrides, by decreasing priority
+}
+
+// resolve resolves the endpoints of the OCI services for a region, see resolveServiceEndpoints.
+//
+// Parameters:
+//   - region: The region of the endpoints.
+//
+// Returns:
+//   - serviceEndpoints: The endpoints to set on the clients.
+func (e endpointSettings) resolve(region string) serviceEndpoints {
+	return resolveServiceEndpoints(region, e.CustomDomain, e.Overrides...)
+}
+
+// resolveServiceEndpoints resolves the endpoint of each OCI service used by the plugin.
+//
+// For each service, the first explicit override wins, the profile override being passed before the
+// datasource override. Otherwise, when a custom domain is configured for a dedicated region, the endpoint
+// is built from the service template with the custom domain as realm domain. Otherwise the endpoint is
+// left to the OCI SDK, which resolves the realm domain of the known regions, including OC2, OC3 and the
+// sovereign realms.
+//
+// Parameters:
+//   - region: The region of the tenancy, the custom region for dedicated regions.
+//   - customDomain: The realm domain of a dedicated region, empty for known regions.
+//   - overrides: The endpoint overrides, by decreasing priority.
+//
+// Returns:
+//   - serviceEndpoints: The endpoints to set on the clients.
+func resolveServiceEndpoints(region string, customDomain string, overrides ...models.EndpointSettings) serviceEndpoints {
+	resolve := func(template string, override func(models.EndpointSettings) string) string {
+		for _, o := range overrides {
+			if endpoint := strings.TrimSpace(override(o)); endpoint != "" {
+				if !strings.Contains(endpoint, "://") {
+					endpoint = "https://" + endpoint
+				}
+				return strings.TrimSuffix(endpoint, "/")
+			}
+		}
+		if customDomain != "" && region != "" {
+			return strings.NewReplacer("{region}", region, "{secondLevelDomain}", strings.Trim(customDomain, ".")).Replace(template)
+		}
+		return ""
+	}
+
+	return serviceEndpoints{
+		loggingSearch:     resolve(endpointTemplateLoggingSearch, func(e models.EndpointSettings) string { return e.LoggingSearch }),
+		loggingManagement: resolve(endpointTemplateLoggingManagement, func(e models.EndpointSettings) string { return e.LoggingManagement }),
+		identity:          resolve(endpointTemplateIdentity, func(e models.EndpointSettings) string { return e.Identity }),
+	}
+}
+
+// applyEndpoints sets the resolved endpoints on the clients of a tenancy.
+//
+// Parameters:
+//   - endpoints: The resolved endpoints, empty endpoints are left unchanged.
+func (ta *logTenancyAccess) applyEndpoints(endpoints serviceEndpoints) {
+	if endpoints.loggingSearch != "" {
+		ta.loggingSearchClient.Host = endpoints.loggingSearch
+	}
+	if endpoints.loggingManagement != "" {
+		ta.loggingManagementClient.Host = endpoints.loggingManagement
+	}
+	if endpoints.identity != "" {
+		ta.identityClient.Host = endpoints.identity
+	}
+	backend.Logger.Debug("plugin.endpoints", "loggingSearchClient.Host", ta.loggingSearchClient.Host,
+		"loggingManagementClient.Host", ta.loggingManagementClient.Host, "identityClient.Host", ta.identityClient.Host)
+}
+
+// loggingManagementClientFor returns the logging management client of a tenancy for a region. The client
+// of the tenancy is returned as is for its own region, and a copy pointing to the endpoint of the region
+// otherwise. The endpoint is resolved from the endpoint settings of the tenancy, so that an endpoint
+// override or the custom domain of a dedicated realm is kept, and from the realm of the region by the
+// OCI SDK otherwise. The copy shares the signer and the HTTP client.
+//
+// Parameters:
+//   - region: The region of the request, the region of the tenancy when empty or all-subscribed-region.
+//
+// Returns:
+//   - logging.LoggingManagementClient: The client to use for the region.
+func (ta *logTenancyAccess) loggingManagementClientFor(region string) logging.LoggingManagementClient {
+	client := ta.loggingManagementClient
+	if region == "" || region == constants.ALL_REGION {
+		return client
+	}
+	if configured, err := ta.config.Region(); err == nil && configured == region {
+		return client
+	}
+	if endpoint := ta.endpoints.resolve(region).loggingManagement; endpoint != "" {
+		client.Host = endpoint
+	} else {
+		client.SetRegion(region)
+	}
+	return client
+}
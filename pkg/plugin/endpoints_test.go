@@ -0,0 +1,184 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/oracle/oci-go-sdk/v65/logging"
+
+	"github.com/oracle/oci-grafana-logs/pkg/plugin/constants"
+	"github.com/oracle/oci-grafana-logs/pkg/plugin/models"
+)
+
+func TestResolveServiceEndpoints(t *testing.T) {
+	profile := models.EndpointSettings{LoggingSearch: "https://search.profile.example.com"}
+	datasource := models.EndpointSettings{
+		LoggingSearch:     "https://search.datasource.example.com",
+		LoggingManagement: "management.datasource.example.com/",
+	}
+
+	tests := []struct {
+		name         string
+		region       string
+		customDomain string
+		overrides    []models.EndpointSettings
+		want         serviceEndpoints
+	}{
+		{
+			name: "SDK default without override nor custom domain",
+			want: serviceEndpoints{},
+		},
+		{
+			name:         "custom domain of a dedicated region",
+			region:       "us-dedicated-1",
+			customDomain: ".oci.example.com.",
+			want: serviceEndpoints{
+				loggingSearch:     "https://logging.us-dedicated-1.oci.oci.example.com",
+				loggingManagement: "https://logging.us-dedicated-1.oci.oci.example.com",
+				identity:          "https://identity.us-dedicated-1.oci.oci.example.com",
+			},
+		},
+		{
+			name:         "custom domain without region is ignored",
+			customDomain: "oci.example.com",
+			want:         serviceEndpoints{},
+		},
+		{
+			name:      "datasource override with scheme and trailing slash normalized",
+			overrides: []models.EndpointSettings{datasource},
+			want: serviceEndpoints{
+				loggingSearch:     "https://search.datasource.example.com",
+				loggingManagement: "https://management.datasource.example.com",
+			},
+		},
+		{
+			name:         "datasource override over custom domain",
+			region:       "us-dedicated-1",
+			customDomain: "oci.example.com",
+			overrides:    []models.EndpointSettings{{}, datasource},
+			want: serviceEndpoints{
+				loggingSearch:     "https://search.datasource.example.com",
+				loggingManagement: "https://management.datasource.example.com",
+				identity:          "https://identity.us-dedicated-1.oci.oci.example.com",
+			},
+		},
+		{
+			name:         "profile override over datasource override",
+			region:       "us-dedicated-1",
+			customDomain: "oci.example.com",
+			overrides:    []models.EndpointSettings{profile, datasource},
+			want: serviceEndpoints{
+				loggingSearch:     "https://search.profile.example.com",
+				loggingManagement: "https://management.datasource.example.com",
+				identity:          "https://identity.us-dedicated-1.oci.oci.example.com",
+			},
+		},
+		{
+			name:      "blank override is not an override",
+			overrides: []models.EndpointSettings{{Identity: "  "}},
+			want:      serviceEndpoints{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolveServiceEndpoints(tt.region, tt.customDomain, tt.overrides...); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("resolveServiceEndpoints() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadSettingsCustomDomain(t *testing.T) {
+	privateKey := string(readTestKey(t, "pkcs1.pem"))
+	secured := map[string]string{}
+	for _, suffix := range []string{"0", "1"} {
+		secured["tenancy"+suffix] = "ocid1.tenancy.oc1..a"
+		secured["user"+suffix] = "ocid1.user.oc1..a"
+		secured["fingerprint"+suffix] = testKeyFingerprint
+		secured["privkey"+suffix] = privateKey
+		secured["customdomain"+suffix] = "oci.example.com"
+	}
+	settings, _ := json.Marshal(models.OCIDatasourceSettings{
+		TenancyMode: "multitenancy",
+		Profiles: []models.ProfileSettings{
+			{Name: "DEFAULT", Region: "us-ashburn-1"},
+			{Name: "DEDICATED", Dedicated: true, CustomRegion: "us-dedicated-1"},
+		},
+	})
+
+	q, err := OCILoadSettings(backend.DataSourceInstanceSettings{JSONData: settings, DecryptedSecureJSONData: secured})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := q.customdomain["DEFAULT"]; got != "" {
+		t.Fatalf("custom domain of a profile without dedicated region = %q, want none", got)
+	}
+	if got := q.customdomain["DEDICATED"]; got != "oci.example.com" {
+		t.Fatalf("custom domain of a dedicated region profile = %q, want oci.example.com", got)
+	}
+}
+
+func TestEndpointSettingsHash(t *testing.T) {
+	a := endpointSettings{Overrides: []models.EndpointSettings{{LoggingSearch: "https://a.example.com"}}}
+	b := endpointSettings{Overrides: []models.EndpointSettings{{LoggingSearch: "https://b.example.com"}}}
+	if settingsHash(a) == settingsHash(b) {
+		t.Fatalf("settingsHash() is the same for different endpoint overrides, the clients would not be rebuilt")
+	}
+}
+
+func TestLoggingManagementClientFor(t *testing.T) {
+	provider := common.NewRawConfigurationProvider("tenancy", "user", "us-ashburn-1", "fingerprint", "", nil)
+	tests := []struct {
+		name      string
+		endpoints endpointSettings
+		region    string
+		want      string
+	}{
+		{name: "no region", region: "", want: "https://logging.example.com"},
+		{name: "all subscribed regions", region: constants.ALL_REGION, want: "https://logging.example.com"},
+		{name: "region of the tenancy", region: "us-ashburn-1", want: "https://logging.example.com"},
+		{name: "other region", region: "eu-frankfurt-1", want: "https://logging.eu-frankfurt-1.oci.oraclecloud.com"},
+		{
+			name:      "other region of a dedicated realm",
+			endpoints: endpointSettings{CustomDomain: "oci.example.com"},
+			region:    "us-dedicated-2",
+			want:      "https://logging.us-dedicated-2.oci.oci.example.com",
+		},
+		{
+			name:      "other region with a management endpoint override",
+			endpoints: endpointSettings{Overrides: []models.EndpointSettings{{LoggingManagement: "https://private.example.com"}}},
+			region:    "eu-frankfurt-1",
+			want:      "https://private.example.com",
+		},
+		{
+			name:      "other region with an override of another service",
+			endpoints: endpointSettings{Overrides: []models.EndpointSettings{{LoggingSearch: "https://private.example.com"}}},
+			region:    "eu-frankfurt-1",
+			want:      "https://logging.eu-frankfurt-1.oci.oraclecloud.com",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ta := &logTenancyAccess{
+				loggingManagementClient: logging.LoggingManagementClient{BaseClient: common.BaseClient{Host: "https://logging.example.com"}},
+				config:                  provider,
+				endpoints:               tt.endpoints,
+			}
+			if got := ta.loggingManagementClientFor(tt.region).Host; got != tt.want {
+				t.Errorf("loggingManagementClientFor(%q).Host = %q, want %q", tt.region, got, tt.want)
+			}
+			if ta.loggingManagementClient.Host != "https://logging.example.com" {
+				t.Errorf("the client of the tenancy was changed, Host = %q", ta.loggingManagementClient.Host)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/pkg/errors"
+
+	"github.com/oracle/oci-grafana-logs/pkg/plugin/constants"
+	"github.com/oracle/oci-grafana-logs/pkg/plugin/models"
+)
+
+// newHTTPDispatcher creates the HTTP client used by the OCI clients of a datasource, routing the requests
+// through the HTTP(S) proxy of the settings or through the Grafana secure socks proxy when the datasource
+// enables it, and applying the TLS settings: custom root CAs, client certificate and minimum TLS version.
+// Without proxy and TLS settings, no client is created and the OCI clients keep the SDK dispatcher.
+//
+// Parameters:
+//   - ctx: The context of the instance creation, holding the Grafana configuration of the secure socks proxy.
+//   - settings: The datasource instance settings, holding the secure socks proxy settings, the proxy password
+//     and the PEM encoded CA bundle, client certificate and client key.
+//   - dsSettings: The loaded datasource settings.
+//
+// Returns:
+//   - *http.Client: The HTTP client, nil when no proxy and no TLS setting is configured.
+//   - string: The hash of the proxy and TLS settings, to rebuild the clients when they change.
+//   - error: An error if the proxy or TLS settings are invalid, e.g. a CA bundle without any certificate.
+func newHTTPDispatcher(ctx context.Context, settings backend.DataSourceInstanceSettings, dsSettings *models.OCIDatasourceSettings) (*http.Client, string, error) {
+	proxyPassword := settings.DecryptedSecureJSONData["httpProxyPassword"]
+	caBundle := strings.TrimSpace(settings.DecryptedSecureJSONData["tlsCACert"])
+	clientCert := strings.TrimSpace(settings.DecryptedSecureJSONData["tlsClientCert"])
+	clientKey := strings.TrimSpace(settings.DecryptedSecureJSONData["tlsClientKey"])
+	if dsSettings.HTTPProxyURL == "" && !dsSettings.EnableSecureSocksProxy && caBundle == "" && clientCert == "" && clientKey == "" &&
+		dsSettings.TLSMinVersion == "" {
+		return nil, "", nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	tlsConfig, err := newTLSConfig(dsSettings.TLSMinVersion, caBundle, clientCert, clientKey)
+	if err != nil {
+		return nil, "", err
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	if dsSettings.HTTPProxyURL != "" {
+		proxyURL, err := url.Parse(dsSettings.HTTPProxyURL)
+		if err != nil {
+			return nil, "", errors.Wrap(err, "invalid HTTP proxy URL")
+		}
+		if dsSettings.HTTPProxyUsername != "" {
+			proxyURL.User = url.UserPassword(dsSettings.HTTPProxyUsername, proxyPassword)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+		backend.Logger.Debug("plugin.http_client", "newHTTPDispatcher", "using HTTP proxy", "proxy", proxyURL.Redacted())
+	}
+
+	if dsSettings.EnableSecureSocksProxy {
+		proxyClient, err := settings.ProxyClient(ctx)
+		if err != nil {
+			return nil, "", errors.Wrap(err, "error with secure socks proxy")
+		}
+		if !proxyClient.SecureSocksProxyEnabled() {
+			return nil, "", errors.New("the secure socks proxy is enabled on the datasource but not on the Grafana server")
+		}
+		if err := proxyClient.ConfigureSecureSocksHTTPProxy(transport); err != nil {
+			return nil, "", errors.Wrap(err, "error with secure socks proxy")
+		}
+		backend.Logger.Debug("plugin.http_client", "newHTTPDispatcher", "using secure socks proxy")
+	}
+
+	client := &http.Client{
+		Timeout:   constants.HTTPClientTimeout * time.Second,
+		Transport: transport,
+	}
+	hash := settingsHash(dsSettings.HTTPProxyURL, dsSettings.HTTPProxyUsername, proxyPassword, dsSettings.EnableSecureSocksProxy,
+		dsSettings.TLSMinVersion, caBundle, clientCert, clientKey)
+	if proxyOptions, err := settings.ProxyOptions(nil); err == nil && proxyOptions != nil {
+		hash = settingsHash(hash, proxyOptions.Auth, proxyOptions.Timeouts)
+	}
+
+	return client, hash, nil
+}
+
+// tlsVersions maps the minimum TLS version setting to the TLS versions of the crypto/tls package.
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// newTLSConfig creates the TLS configuration of the OCI clients.
+//
+// Parameters:
+//   - minVersion: The minimum TLS version, "1.2" or "1.3", TLS 1.2 when empty.
+//   - caBundle: The PEM encoded root CAs trusted in addition to the system roots, empty for the system roots only.
+//   - clientCert: The PEM encoded client certificate, empty for none.
+//   - clientKey: The PEM encoded private key of the client certificate.
+//
+// Returns:
+//   - *tls.Config: The TLS configuration.
+//   - error: An error naming the invalid setting.
+func newTLSConfig(minVersion string, caBundle string, clientCert string, clientKey string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if minVersion != "" {
+		version, ok := tlsVersions[minVersion]
+		if !ok {
+			return nil, errors.Errorf("unsupported minimum TLS version %q, use 1.2 or 1.3", minVersion)
+		}
+		tlsConfig.MinVersion = version
+	}
+
+	if caBundle != "" {
+		rootCAs, err := x509.SystemCertPool()
+		if err != nil || rootCAs == nil {
+			rootCAs = x509.NewCertPool()
+		}
+		count, err := appendCertificates(rootCAs, []byte(caBundle))
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid CA bundle")
+		}
+		if count == 0 {
+			return nil, errors.New("invalid CA bundle: no PEM certificate found")
+		}
+		tlsConfig.RootCAs = rootCAs
+		backend.Logger.Debug("plugin.http_client", "newTLSConfig", "custom root CAs loaded", "certificates", count)
+	}
+
+	switch {
+	case clientCert != "" && clientKey != "":
+		certificate, err := tls.X509KeyPair([]byte(clientCert), []byte(clientKey))
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid client certificate")
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	case clientCert != "":
+		return nil, errors.New("invalid client certificate: the client key is missing")
+	case clientKey != "":
+		return nil, errors.New("invalid client certificate: the client certificate is missing")
+	}
+
+	return tlsConfig, nil
+}
+
+// appendCertificates adds the certificates of a PEM bundle to a certificate pool, failing on the first
+// certificate that cannot be parsed rather than skipping it as x509.CertPool.AppendCertsFromPEM does.
+//
+// Parameters:
+//   - pool: The certificate pool.
+//   - bundle: The PEM encoded certificates.
+//
+// Returns:
+//   - int: The number of certificates added.
+//   - error: An error naming the certificate that cannot be parsed.
+func appendCertificates(pool *x509.CertPool, bundle []byte) (int, error) {
+	count := 0
+	for block, rest := pem.Decode(bundle); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return count, errors.Wrapf(err, "certificate #%d", count+1)
+		}
+		pool.AddCert(certificate)
+		count++
+	}
+	return count, nil
+}
+
+// federationDispatcher dispatches the requests of the instance principal provider: the requests to the
+// instance metadata service, on a link-local address, are sent directly, and the token requests to the OCI
+// authentication service go through the HTTP client of the datasource, like the requests of the OCI clients.
+type federationDispatcher struct {
+	direct  common.HTTPRequestDispatcher
+	proxied *http.Client
+}
+
+// Do sends a request of the instance principal provider.
+func (d federationDispatcher) Do(request *http.Request) (*http.Response, error) {
+	if ip := net.ParseIP(request.URL.Hostname()); ip != nil && ip.IsLinkLocalUnicast() {
+		return d.direct.Do(request)
+	}
+	return d.proxied.Do(request)
+}
+
+// federationClientModifier returns the modifier of the HTTP dispatchers of the instance principal provider,
+// so that its token requests use the proxy and TLS settings of the datasource.
+//
+// Parameters:
+//   - httpClient: The HTTP client of the datasource, nil to keep the OCI SDK dispatchers.
+//
+// Returns:
+//   - The modifier of the dispatchers of the instance principal provider.
+func federationClientModifier(httpClient *http.Client) func(common.HTTPRequestDispatcher) (common.HTTPRequestDispatcher, error) {
+	return func(dispatcher common.HTTPRequestDispatcher) (common.HTTPRequestDispatcher, error) {
+		if httpClient == nil {
+			return dispatcher, nil
+		}
+		return federationDispatcher{direct: dispatcher, proxied: httpClient}, nil
+	}
+}
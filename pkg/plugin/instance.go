@@ -0,0 +1,245 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/oracle/oci-go-sdk/v65/common"
+
+	"github.com/oracle/oci-grafana-logs/pkg/plugin/models"
+)
+
+// registeredTenancy holds the clients of a tenancy with the hash of the settings they were built from.
+type registeredTenancy struct {
+	hash   string
+	access *logTenancyAccess
+}
+
+// registeredDatasource holds the clients of the current instance of a datasource, indexed by tenancy id,
+// with the generation of the registration.
+type registeredDatasource struct {
+	generation uint64
+	tenancies  map[string]registeredTenancy
+}
+
+// tenancyRegistry keeps the clients of the current instance of each datasource, indexed by datasource UID
+// and tenancy id, so that a new instance created on a settings change rebuilds only the clients of the
+// tenancies whose settings changed. Each registration gets a new generation, so that a disposed instance
+// removes the clients of its datasource only when no newer instance replaced them.
+type tenancyRegistry struct {
+	mu          sync.Mutex
+	generation  uint64
+	datasources map[string]registeredDatasource
+}
+
+// clientRegistry is the registry shared by all the datasource instances of the plugin.
+var clientRegistry = &tenancyRegistry{datasources: make(map[string]registeredDatasource)}
+
+// snapshot returns the tenancies registered for a datasource.
+func (r *tenancyRegistry) snapshot(uid string) map[string]registeredTenancy {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tenancies := make(map[string]registeredTenancy, len(r.datasources[uid].tenancies))
+	for id, tenancy := range r.datasources[uid].tenancies {
+		tenancies[id] = tenancy
+	}
+	return tenancies
+}
+
+// replace registers the tenancies of the new instance of a datasource and returns the generation of
+// the registration.
+func (r *tenancyRegistry) replace(uid string, tenancies map[string]registeredTenancy) uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.generation++
+	r.datasources[uid] = registeredDatasource{generation: r.generation, tenancies: tenancies}
+	return r.generation
+}
+
+// remove unregisters the tenancies of a datasource, unless they were replaced since the given generation
+// was registered. It tells whether the tenancies were removed.
+func (r *tenancyRegistry) remove(uid string, generation uint64) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if registered, ok := r.datasources[uid]; !ok || registered.generation != generation {
+		return false
+	}
+	delete(r.datasources, uid)
+	return true
+}
+
+// inUse tells whether the clients of a tenancy are registered for a datasource.
+func (r *tenancyRegistry) inUse(uid string, access *logTenancyAccess) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, tenancy := range r.datasources[uid].tenancies {
+		if tenancy.access == access {
+			return true
+		}
+	}
+	return false
+}
+
+// settingsHash returns the hash of the settings a tenancy's clients are built from, so that secrets
+// such as the private key are compared without being kept.
+//
+// Parameters:
+//   - settings: The settings the clients are built from.
+//
+// Returns:
+//   - string: The hex encoded SHA-256 of the settings.
+func settingsHash(settings ...interface{}) string {
+	content, err := jsoniter.Marshal(settings)
+	if err != nil {
+		// Settings that can not be hashed are never reused
+		return ""
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// tenancyAccessFor returns the clients of a tenancy, reusing the clients of the previous instance of the
+// datasource when the tenancy was built from the same settings, and building them otherwise.
+// The clients are recorded to be registered once the whole instance is built.
+//
+// Parameters:
+//   - id: The id of the tenancy within the datasource, e.g. the profile name.
+//   - hash: The hash of the settings of the tenancy, see settingsHash.
+//   - build: Builds the clients of the tenancy.
+//
+// Returns:
+//   - *logTenancyAccess: The clients of the tenancy.
+//   - error: An error if the clients cannot be built.
+func (o *OCIDatasource) tenancyAccessFor(id string, hash string, build func() (*logTenancyAccess, error)) (*logTenancyAccess, error) {
+	// The clients are also rebuilt when the proxy or TLS settings change
+	if hash != "" {
+		hash = settingsHash(hash, o.httpClientHash)
+	}
+	if previous, ok := o.previousTenancies[id]; ok && hash != "" && previous.hash == hash {
+		backend.Logger.Debug("plugin.instance", "tenancyAccessFor", "settings unchanged, reusing clients", "tenancy", id)
+		o.builtTenancies[id] = previous
+		return previous.access, nil
+	}
+
+	backend.Logger.Debug("plugin.instance", "tenancyAccessFor", "building clients", "tenancy", id)
+	access, err := build()
+	if err != nil {
+		return nil, err
+	}
+	o.builtTenancies[id] = registeredTenancy{hash: hash, access: access}
+	return access, nil
+}
+
+// CallResource handles the resource calls, tracked as in-flight requests of the instance.
+func (o *OCIDatasource) CallResource(ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) error {
+	o.inflight.RLock()
+	defer o.inflight.RUnlock()
+
+	return o.CallResourceHandler.CallResource(ctx, req, sender)
+}
+
+// Dispose implements instancemgmt.InstanceDisposer. It is called by the instance manager once the instance
+// is replaced by an instance with the new settings of the datasource, or the datasource is deleted. The live
+// tail streams of the instance are stopped at once, and its other resources are released after its in-flight
+// requests complete: the clients are unregistered unless a new instance replaced them, and the connections
+// of the clients that the new instance did not reuse are closed.
+func (o *OCIDatasource) Dispose() {
+	o.stopStreams()
+
+	go func() {
+		o.inflight.Lock()
+		defer o.inflight.Unlock()
+
+		removed := clientRegistry.remove(o.uid, o.generation)
+		closed := 0
+		for _, access := range o.tenancyAccess {
+			if clientRegistry.inUse(o.uid, access) {
+				continue
+			}
+			access.closeIdleConnections()
+			closed++
+		}
+		if o.cache != nil {
+			o.cache.Close()
+		}
+		backend.Logger.Debug("plugin.instance", "Dispose", "instance disposed", "datasource", o.uid, "unregistered", removed, "closedTenancies", closed)
+	}()
+}
+
+// closeIdleConnections closes the idle connections of the clients of a tenancy.
+func (ta *logTenancyAccess) closeIdleConnections() {
+	for _, dispatcher := range []common.HTTPRequestDispatcher{
+		ta.loggingSearchClient.HTTPClient,
+		ta.loggingManagementClient.HTTPClient,
+		ta.identityClient.HTTPClient,
+	} {
+		if client, ok := dispatcher.(*http.Client); ok {
+			client.CloseIdleConnections()
+		}
+	}
+}
+
+// credentialsReloader is implemented by the configuration providers reading credentials that may be
+// rotated outside of Grafana, such as the key file of an OCI config file or the token of an OCI CLI session.
+type credentialsReloader interface {
+	ReloadCredentials() error
+}
+
+// revalidateCredentials reads again the credentials of each tenancy whose provider supports it, then tests
+// the connectivity with the reloaded credentials. A tenancy whose credentials cannot be read again keeps
+// its previous credentials, so that in-flight and later queries keep working.
+//
+// Parameters:
+//   - ctx: The context of the request.
+//
+// Returns:
+//   - models.CredentialsValidation: The reload result of each tenancy and the connectivity test result.
+func (o *OCIDatasource) revalidateCredentials(ctx context.Context) models.CredentialsValidation {
+	result := models.CredentialsValidation{Status: "ok"}
+
+	keys := make([]string, 0, len(o.tenancyAccess))
+	for key := range o.tenancyAccess {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		tenancy := models.TenancyCredentials{Tenancy: key}
+		if reloader, ok := o.tenancyAccess[key].config.(credentialsReloader); ok {
+			if err := reloader.ReloadCredentials(); err != nil {
+				backend.Logger.Error("plugin.instance", "revalidateCredentials", "could not reload credentials", "tenancy", key, "error", err)
+				tenancy.Error = err.Error()
+				result.Status = "error"
+			} else {
+				tenancy.Reloaded = true
+			}
+		}
+		result.Tenancies = append(result.Tenancies, tenancy)
+	}
+
+	if err := o.TestConnectivity(ctx); err != nil {
+		result.Status = "error"
+		result.Message = err.Error()
+	} else if result.Status == "ok" {
+		result.Message = "Credentials are valid"
+	} else {
+		result.Message = "Some credentials could not be reloaded, the previous credentials are kept"
+	}
+
+	return result
+}
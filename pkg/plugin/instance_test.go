@@ -0,0 +1,37 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"testing"
+)
+
+func TestTenancyRegistryRemove(t *testing.T) {
+	registry := &tenancyRegistry{datasources: make(map[string]registeredDatasource)}
+	access := &logTenancyAccess{}
+
+	first := registry.replace("uid", map[string]registeredTenancy{"DEFAULT": {access: access}})
+	second := registry.replace("uid", map[string]registeredTenancy{"DEFAULT": {access: access}})
+
+	// The disposed first instance must not unregister the clients of the second instance
+	if registry.remove("uid", first) {
+		t.Fatal("remove() of a replaced generation removed the tenancies")
+	}
+	if !registry.inUse("uid", access) {
+		t.Fatal("the tenancies of the current instance are no longer registered")
+	}
+
+	// The datasource is deleted, the last instance unregisters its clients
+	if !registry.remove("uid", second) {
+		t.Fatal("remove() of the current generation did not remove the tenancies")
+	}
+	if registry.inUse("uid", access) || len(registry.snapshot("uid")) != 0 {
+		t.Fatal("the tenancies of a deleted datasource are still registered")
+	}
+	if registry.remove("uid", second) {
+		t.Fatal("remove() of an unknown datasource removed tenancies")
+	}
+}
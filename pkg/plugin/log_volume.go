@@ -0,0 +1,186 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/oracle/oci-grafana-logs/pkg/plugin/constants"
+	"github.com/oracle/oci-grafana-logs/pkg/plugin/models"
+)
+
+// Candidate intervals for the log volume histogram, from the finest to the coarsest,
+// expressed as rounddown() intervals
+var logVolumeIntervals = []struct {
+	rounddown string
+	duration  time.Duration
+}{
+	{"1m", time.Minute},
+	{"5m", 5 * time.Minute},
+	{"10m", 10 * time.Minute},
+	{"15m", 15 * time.Minute},
+	{"30m", 30 * time.Minute},
+	{"1h", time.Hour},
+	{"3h", 3 * time.Hour},
+	{"6h", 6 * time.Hour},
+	{"12h", 12 * time.Hour},
+	{"1d", 24 * time.Hour},
+	{"7d", 7 * 24 * time.Hour},
+}
+
+// Log record fields which may hold the level of a log record, in order of preference
+var logVolumeLevelFields = []string{
+	"data.level",
+	"data.severity",
+	"data.logLevel",
+	"data.loglevel",
+	"data.log_level",
+	"level",
+	"severity",
+}
+
+// Pipeline commands of a log records query which do not make sense once the query is summarized
+var reLogVolumeDroppedCommand = regexp.MustCompile(`^(?i)(sort|limit|head|tail)\b`)
+
+// Pipeline commands of a log records query which project, rename or aggregate the log records: the
+// commands from the first of them are not part of the log volume query, since they may remove the
+// datetime field counted by the histogram, and a summarized query cannot be summarized again
+var reLogVolumeTruncatingCommand = regexp.MustCompile(`^(?i)(fields|eval|rename|stats|summarize|dedup|top)\b`)
+
+// logVolumeQuery rewrites a log records query into the query of its log volume histogram, i.e.
+// the count of log records per rounddown bucket, grouped by level when the log records have a
+// level field.
+//
+// Parameters:
+//   - ctx: The context for the request execution.
+//   - queryModel: The query model of the log records query.
+//   - fromMs: The start time in milliseconds since Unix epoch.
+//   - toMs: The end time in milliseconds since Unix epoch.
+//   - takey: The tenancy key for accessing the appropriate OCI client.
+//
+// Returns:
+//   - The log volume search query.
+func (o *OCIDatasource) logVolumeQuery(ctx context.Context, queryModel *models.QueryModel, fromMs int64, toMs int64, takey string) string {
+	baseQuery := logVolumeBaseQuery(queryModel.QueryText)
+
+	interval := logVolumeInterval(time.Duration(toMs-fromMs) * time.Millisecond)
+	summarize := fmt.Sprintf("summarize count() as count by rounddown(datetime, '%s') as interval", interval)
+	if levelField := o.detectLevelField(ctx, baseQuery, fromMs, toMs, takey); levelField != "" {
+		summarize += ", " + levelField + " as level"
+	}
+
+	return baseQuery + " | " + summarize
+}
+
+// logVolumeBaseQuery returns the part of a log records query selecting the log records counted by its
+// log volume histogram: the pipeline up to the first command projecting or aggregating the log records,
+// without the commands sorting or limiting them.
+//
+// Parameters:
+//   - searchQuery: The log records query.
+//
+// Returns:
+//   - The query selecting the log records to count.
+func logVolumeBaseQuery(searchQuery string) string {
+	commands := make([]string, 0)
+	for _, command := range splitQueryPipeline(searchQuery) {
+		if reLogVolumeTruncatingCommand.MatchString(command) {
+			break
+		}
+		if command != "" && !reLogVolumeDroppedCommand.MatchString(command) {
+			commands = append(commands, command)
+		}
+	}
+
+	return strings.Join(commands, " | ")
+}
+
+// logVolumeInterval returns the finest rounddown interval producing at most LogVolumeMaxBuckets
+// buckets over the given time range.
+//
+// Parameters:
+//   - timeRange: The duration of the query time range.
+//
+// Returns:
+//   - The rounddown interval, e.g. "5m".
+func logVolumeInterval(timeRange time.Duration) string {
+	for _, interval := range logVolumeIntervals {
+		if timeRange/interval.duration <= constants.LogVolumeMaxBuckets {
+			return interval.rounddown
+		}
+	}
+
+	return logVolumeIntervals[len(logVolumeIntervals)-1].rounddown
+}
+
+// detectLevelField looks for a level field in a sample of the log records returned by a query. The
+// detected field, or its absence, is cached per tenancy and query text so that refreshing a panel does
+// not search the sample again.
+//
+// Parameters:
+//   - ctx: The context for the request execution.
+//   - searchQuery: The log records query.
+//   - fromMs: The start time in milliseconds since Unix epoch.
+//   - toMs: The end time in milliseconds since Unix epoch.
+//   - takey: The tenancy key for accessing the appropriate OCI client.
+//
+// Returns:
+//   - The path of the level field, or an empty string if none was found.
+func (o *OCIDatasource) detectLevelField(ctx context.Context, searchQuery string, fromMs int64, toMs int64, takey string) string {
+	cacheKey := "logVolumeLevelField|" + takey + "|" + searchQuery
+	if o.cache != nil {
+		if cached, found := o.cache.Get(cacheKey); found {
+			return cached.(string)
+		}
+	}
+
+	levelField, err := o.sampleLevelField(ctx, searchQuery, fromMs, toMs, takey)
+	if err != nil {
+		o.logger.Warn("Unable to sample log records to detect the level field", "error", err)
+		return ""
+	}
+	if o.cache != nil {
+		o.cache.SetWithTTL(cacheKey, levelField, 1, constants.LogVolumeLevelFieldCacheTTL*time.Second)
+	}
+
+	return levelField
+}
+
+// sampleLevelField searches a sample of the log records returned by a query and returns the first
+// level field found in it.
+//
+// Parameters:
+//   - ctx: The context for the request execution.
+//   - searchQuery: The log records query.
+//   - fromMs: The start time in milliseconds since Unix epoch.
+//   - toMs: The end time in milliseconds since Unix epoch.
+//   - takey: The tenancy key for accessing the appropriate OCI client.
+//
+// Returns:
+//   - The path of the level field, or an empty string if none was found.
+//   - An error if the sample could not be searched.
+func (o *OCIDatasource) sampleLevelField(ctx context.Context, searchQuery string, fromMs int64, toMs int64, takey string) (string, error) {
+	logRecords, err := o.searchLogRecords(ctx, takey, searchQuery, time.UnixMilli(fromMs), time.UnixMilli(toMs),
+		constants.LogVolumeLevelProbeRecords)
+	if err != nil {
+		return "", err
+	}
+
+	for _, levelField := range logVolumeLevelFields {
+		for _, logRecord := range logRecords {
+			if value, ok := lookupLogField(logRecord, levelField); ok && value != nil {
+				o.logger.Debug("Detected log volume level field", "field", levelField)
+				return levelField, nil
+			}
+		}
+	}
+
+	return "", nil
+}
@@ -0,0 +1,95 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/ristretto"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+
+	"github.com/oracle/oci-grafana-logs/pkg/plugin/models"
+)
+
+func TestLogVolumeBaseQuery(t *testing.T) {
+	const search = `search "ocid1.compartment.oc1..a/ocid1.loggroup.oc1..b"`
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{name: "search only", query: search, want: search},
+		{name: "filters are kept", query: search + " | where level = 'ERROR'", want: search + " | where level = 'ERROR'"},
+		{name: "sort and limits are dropped", query: search + " | sort by datetime desc | where a = 1 | head 10 | tail 5 | limit 3", want: search + " | where a = 1"},
+		{name: "fields truncates", query: search + " | fields data.message | where a = 1", want: search},
+		{name: "eval truncates", query: search + " | where a = 1 | eval b = 2 | where b = 2", want: search + " | where a = 1"},
+		{name: "rename truncates", query: search + " | rename data.message as message", want: search},
+		{name: "stats truncates", query: search + " | stats count() by type", want: search},
+		{name: "summarize truncates", query: search + " | summarize count() by type", want: search},
+		{name: "dedup truncates", query: search + " | dedup data.message", want: search},
+		{name: "top truncates", query: search + " | top 5 type", want: search},
+		{name: "commands are matched case insensitively", query: search + " | SORT BY datetime | FIELDS type", want: search},
+		{name: "commands within quotes are kept", query: search + " | where data.message = 'a | fields b'", want: search + " | where data.message = 'a | fields b'"},
+		{name: "command prefixes are not commands", query: search + " | where fieldsCount > 1", want: search + " | where fieldsCount > 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := logVolumeBaseQuery(tt.query); got != tt.want {
+				t.Fatalf("logVolumeBaseQuery() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogVolumeQuery(t *testing.T) {
+	cache, err := ristretto.NewCache(&ristretto.Config{NumCounters: 1000, MaxCost: 1 << 20, BufferItems: 64})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cache.Close()
+
+	const search = `search "ocid1.compartment.oc1..a"`
+	// The detected level fields are cached per tenancy and base query, so no log search is made
+	cache.Set("logVolumeLevelField|DEFAULT/|"+search, "data.level", 1)
+	cache.Set("logVolumeLevelField|DEFAULT/|"+search+" | where a = 1", "", 1)
+	cache.Wait()
+
+	o := &OCIDatasource{logger: log.DefaultLogger, cache: cache}
+	from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		query     string
+		timeRange time.Duration
+		want      string
+	}{
+		{
+			name:      "grouped by level",
+			query:     search + " | fields data.message | sort by datetime desc",
+			timeRange: time.Hour,
+			want:      search + " | summarize count() as count by rounddown(datetime, '1m') as interval, data.level as level",
+		},
+		{
+			name:      "without level field",
+			query:     search + " | where a = 1 | summarize count() by type",
+			timeRange: 24 * time.Hour,
+			want:      search + " | where a = 1 | summarize count() as count by rounddown(datetime, '15m') as interval",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := o.logVolumeQuery(context.Background(), &models.QueryModel{QueryText: tt.query},
+				from.UnixMilli(), from.Add(tt.timeRange).UnixMilli(), "DEFAULT/")
+			if got != tt.want {
+				t.Fatalf("logVolumeQuery() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
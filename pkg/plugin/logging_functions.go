@@ -7,9 +7,9 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/davecgh/go-spew/spew"
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/oracle/oci-go-sdk/v65/common"
 	"github.com/oracle/oci-go-sdk/v65/identity"
@@ -47,107 +47,191 @@ const numMaxResults = (constants.MaxPagesToFetch * constants.LimitPerPage) + 1
 
 // TestConnectivity checks the OCI data source test request in Grafana's Datasource configuration UI.
 //
-// This function performs a connectivity test to the Oracle Cloud Infrastructure (OCI) Logging service.
-// It validates the configured credentials and permissions by attempting to search logs or list log groups,
-//
-//	depending on the environment.
-//
-// The function iterates through each configured tenancy access key and follows these steps:
-// 1. Fetches the tenancy OCID using the `FetchTenancyOCID` method.
-// 2. Checks if the environment is set to "local":
-//   - Constructs and executes a log search query for recent logs (last 30 minutes).
-//   - Validates the response status to determine success.
-//
-// 3. If the environment is not "local":
-//   - Lists log groups in the given tenancy.
-//   - Validates the response status to determine success.
-//
-// 4. Logs success or failure messages at each step.
+// Every configured tenancy is checked at the same time by checkTenancyHealth, and the connectivity test
+// succeeds only when all the tenancies are healthy.
 //
 // Parameters:
 //   - ctx: The context.Context for the request.
 //
 // Returns:
-//   - error: Returns an error if connectivity checks fail; otherwise, returns nil on success.
+//   - error: An error naming the failing tenancies and the reason of their failure, nil when all are healthy.
 func (o *OCIDatasource) TestConnectivity(ctx context.Context) error {
-	backend.Logger.Debug("client", "TestConnectivity", "testing the OCI connectivity")
+	report, err := o.CheckTenanciesHealth(ctx)
+	if err != nil {
+		return err
+	}
+	if report.Failing > 0 {
+		return errors.New(report.Message)
+	}
+	return nil
+}
 
-	// Retrieve the environment setting.
-	tenv := o.settings.Environment
+// CheckTenanciesHealth checks every configured tenancy concurrently and summarizes the results.
+//
+// Parameters:
+//   - ctx: The context.Context for the request.
+//
+// Returns:
+//   - models.HealthReport: The health of each tenancy, in tenancy key order, and a message naming the
+//     healthy and the failing tenancies.
+//   - error: An error if no tenancy is configured.
+func (o *OCIDatasource) CheckTenanciesHealth(ctx context.Context) (models.HealthReport, error) {
+	backend.Logger.Debug("client", "CheckTenanciesHealth", "testing the OCI connectivity")
 
 	// Ensure tenancy access map is not empty.
 	if len(o.tenancyAccess) == 0 {
-		return fmt.Errorf("TestConnectivity failed: cannot read o.tenancyAccess")
+		return models.HealthReport{}, fmt.Errorf("TestConnectivity failed: cannot read o.tenancyAccess")
 	}
 
-	// Iterate through each configured tenancy.
+	keys := make([]string, 0, len(o.tenancyAccess))
 	for key := range o.tenancyAccess {
-		// Fetch the Tenancy OCID using the key.
-		tenancyocid, tenancyErr := o.FetchTenancyOCID(key)
-		if tenancyErr != nil {
-			return errors.Wrap(tenancyErr, "error fetching TenancyOCID")
-		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	tenancies := make([]models.TenancyHealth, len(keys))
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		wg.Add(1)
+		go func(i int, key string) {
+			defer wg.Done()
+			tenancies[i] = o.checkTenancyHealth(ctx, key)
+		}(i, key)
+	}
+	wg.Wait()
 
-		backend.Logger.Debug("TestConnectivity", "Config Key", key, "Testing Tenancy OCID", tenancyocid)
-		// If running in "local" environment, perform a log search.
-		if tenv == "local" {
-			// Construct a log search query for the given tenancy OCID.
-			queri := `search "` + tenancyocid + `" | sort by datetime desc`
-
-			// Define a time range (last 30 minutes).
-			t := time.Now()
-			t2 := t.Add(-time.Minute * 30)
-			start, _ := time.Parse(time.RFC3339, t2.Format(time.RFC3339))
-			end, _ := time.Parse(time.RFC3339, t.Format(time.RFC3339))
-
-			// Create a log search request.
-			request := loggingsearch.SearchLogsRequest{SearchLogsDetails: loggingsearch.SearchLogsDetails{SearchQuery: common.String(queri),
-				TimeStart:         &common.SDKTime{Time: start},
-				TimeEnd:           &common.SDKTime{Time: end},
-				IsReturnFieldInfo: common.Bool(false)},
-				Limit: common.Int(10)}
-
-			// Execute the log search query.
-			res, err := o.tenancyAccess[key].loggingSearchClient.SearchLogs(ctx, request)
-			if err != nil {
-				backend.Logger.Error("TestConnectivity", "Config Key", key, "SKIPPED", err)
-				return fmt.Errorf("ListLogGroupsRequest failed in each Compartments in profile %v", err)
-			}
+	return summarizeTenanciesHealth(tenancies), nil
+}
 
-			// Validate HTTP response status.
-			status := res.RawResponse.StatusCode
-			if status >= 200 && status < 300 {
-				backend.Logger.Debug("TestConnectivity", "Config Key", key, "OK", status)
-				break
-			} else {
-				o.logger.Debug(key, "SKIPPED", status)
-				return errors.Wrap(err, fmt.Sprintf("ListLogGroupsRequest failed: %s", key))
-			}
+// summarizeTenanciesHealth counts the healthy and the failing tenancies and builds the message of the
+// health report, naming the healthy tenancies and the failing ones with the reason of their failure.
+//
+// Parameters:
+//   - tenancies: The health of each tenancy, in tenancy key order.
+//
+// Returns:
+//   - models.HealthReport: The health report of the tenancies.
+func summarizeTenanciesHealth(tenancies []models.TenancyHealth) models.HealthReport {
+	report := models.HealthReport{Tenancies: tenancies}
+
+	var healthy, failing []string
+	for _, tenancy := range report.Tenancies {
+		if tenancy.Healthy {
+			healthy = append(healthy, tenancy.Name)
 		} else {
-			// For non-local environments, list log groups for the given tenancy OCID.
-			request := logging.ListLogGroupsRequest{Limit: common.Int(69),
-				CompartmentId:            common.String(tenancyocid),
-				IsCompartmentIdInSubtree: common.Bool(true)}
-
-			// Execute the log group listing request.
-			res, err := o.tenancyAccess[key].loggingManagementClient.ListLogGroups(ctx, request)
-			if err != nil {
-				o.logger.Debug(key, "FAILED", err)
-				return errors.Wrap(err, fmt.Sprintf("ListLogGroupsRequest failed:%s", key))
-			}
-			// Validate HTTP response status.
-			status := res.RawResponse.StatusCode
-			if status >= 200 && status < 300 {
-				backend.Logger.Debug("TestConnectivity", "Config Key", key, "OK", status)
-				break
-			} else {
-				backend.Logger.Debug("TestConnectivity", "Config Key", key, "SKIPPED", status)
-				return errors.Wrap(err, fmt.Sprintf("ListLogGroupsRequest failed in each Compartments in profile %s", key))
-			}
+			failing = append(failing, fmt.Sprintf("%s (%s)", tenancy.Name, strings.Join(tenancy.Errors, "; ")))
 		}
+	}
+	report.Healthy, report.Failing = len(healthy), len(failing)
 
+	switch {
+	case len(failing) == 0:
+		report.Message = fmt.Sprintf("Success: %d tenancies healthy: %s", len(healthy), strings.Join(healthy, ", "))
+		if len(healthy) == 1 {
+			report.Message = "Success"
+		}
+	case len(healthy) == 0:
+		report.Message = fmt.Sprintf("All %d tenancies failing: %s", len(failing), strings.Join(failing, ", "))
+	default:
+		report.Message = fmt.Sprintf("%d tenancies healthy: %s. %d tenancies failing: %s",
+			len(healthy), strings.Join(healthy, ", "), len(failing), strings.Join(failing, ", "))
 	}
-	return nil
+
+	return report
+}
+
+// checkTenancyHealth checks the credentials and permissions of a tenancy:
+//
+// 1. Auth: the credentials of the provider are valid, e.g. the session has not expired, and OCI accepts them.
+// 2. Region reachability: the identity endpoint of the region answers, by listing the region subscriptions.
+// 3. Logging search: a log search on the tenancy over the last 30 minutes is permitted.
+// 4. Log group listing: listing the log groups of the tenancy is permitted.
+//
+// A tenancy is healthy when it is authenticated, its region is reachable and log search is permitted.
+// Listing log groups is only needed by the query editor, so a denied listing is reported without failing the tenancy.
+//
+// Parameters:
+//   - ctx: The context.Context for the request.
+//   - key: The tenancy access key.
+//
+// Returns:
+//   - models.TenancyHealth: The result of each check, with the errors of the failed checks.
+func (o *OCIDatasource) checkTenancyHealth(ctx context.Context, key string) models.TenancyHealth {
+	ta := o.tenancyAccess[key]
+	health := models.TenancyHealth{Key: key, Name: strings.SplitN(key, "/", 2)[0]}
+	if health.Name == "" {
+		health.Name = key
+	}
+	health.Region, _ = ta.config.Region()
+
+	fail := func(check string, err error) {
+		health.Errors = append(health.Errors, check+": "+err.Error())
+	}
+
+	tenancyocid, err := o.FetchTenancyOCID(key)
+	if err != nil {
+		health.Auth = models.HealthCheckFailed
+		fail("auth", err)
+		return health
+	}
+	health.TenancyOCID = tenancyocid
+	if err := checkSessionToken(ta.config); err != nil {
+		health.Auth = models.HealthCheckFailed
+		fail("auth", err)
+		return health
+	}
+	if _, err := ta.config.KeyID(); err != nil {
+		health.Auth = models.HealthCheckFailed
+		fail("auth", err)
+		return health
+	}
+
+	// The region subscriptions tell whether the region answers and whether OCI accepts the credentials
+	_, err = ta.identityClient.ListRegionSubscriptions(ctx, identity.ListRegionSubscriptionsRequest{TenancyId: common.String(tenancyocid)})
+	if serviceErr, ok := common.IsServiceError(err); ok {
+		health.RegionReachable = true
+		if serviceErr.GetHTTPStatusCode() == 401 {
+			health.Auth = models.HealthCheckFailed
+			fail("auth", err)
+			return health
+		}
+		// Authenticated, but the listing of the region subscriptions is not permitted
+		health.Auth = models.HealthCheckOK
+	} else if err != nil {
+		health.Auth = models.HealthCheckUnknown
+		fail("region "+health.Region+" unreachable", err)
+		return health
+	} else {
+		health.RegionReachable = true
+		health.Auth = models.HealthCheckOK
+	}
+
+	t := time.Now()
+	searchRequest := loggingsearch.SearchLogsRequest{SearchLogsDetails: loggingsearch.SearchLogsDetails{
+		SearchQuery:       common.String(`search "` + tenancyocid + `" | sort by datetime desc`),
+		TimeStart:         &common.SDKTime{Time: t.Add(-time.Minute * 30)},
+		TimeEnd:           &common.SDKTime{Time: t},
+		IsReturnFieldInfo: common.Bool(false)},
+		Limit: common.Int(1)}
+	if _, err := ta.loggingSearchClient.SearchLogs(ctx, searchRequest); err != nil {
+		fail("logging search", err)
+	} else {
+		health.LoggingSearch = true
+	}
+
+	listRequest := logging.ListLogGroupsRequest{Limit: common.Int(1),
+		CompartmentId:            common.String(tenancyocid),
+		IsCompartmentIdInSubtree: common.Bool(true)}
+	if _, err := ta.loggingManagementClient.ListLogGroups(ctx, listRequest); err != nil {
+		backend.Logger.Warn("TestConnectivity", "Config Key", key, "ListLogGroups", err)
+		health.Warnings = append(health.Warnings, "log group listing: "+err.Error())
+	} else {
+		health.ListLogGroups = true
+	}
+
+	health.Healthy = health.LoggingSearch
+	backend.Logger.Debug("TestConnectivity", "Config Key", key, "Healthy", health.Healthy, "LoggingSearch", health.LoggingSearch, "ListLogGroups", health.ListLogGroups)
+	return health
 }
 
 /*
@@ -166,14 +250,13 @@ Returns:
 func (o *OCIDatasource) FetchTenancyOCID(takey string) (string, error) {
 	tenv := o.settings.Environment
 	tenancymode := o.settings.TenancyMode
-	xtenancy := o.settings.Xtenancy_0
+	var xtenancy string
+	if targets := o.settings.TargetTenancies(); len(targets) > 0 {
+		xtenancy = targets[0].TenancyOCID
+	}
 	var tenancyocid string
 	var tenancyErr error
 
-	if tenancymode == "multitenancy" && tenv == "OCI Instance" {
-		return "", errors.New("Multitenancy mode using instance principals is not implemented yet.")
-	}
-
 	// Handle multitenancy mode
 	if tenancymode == "multitenancy" {
 		// Ensure the tenancy key is valid
@@ -187,11 +270,11 @@ func (o *OCIDatasource) FetchTenancyOCID(takey string) (string, error) {
 		}
 	} else {
 		// Handle single tenancy with possible cross-tenancy instance principal
-		if xtenancy != "" && tenv == "OCI Instance" {
+		if xtenancy != "" && tenv == constants.Environment_Instance {
 			o.logger.Debug("Cross Tenancy Instance Principal detected")
 			tocid, _ := o.tenancyAccess[takey].config.TenancyOCID()
 			o.logger.Debug("Source Tenancy OCID: " + tocid)
-			o.logger.Debug("Target Tenancy OCID: " + o.settings.Xtenancy_0)
+			o.logger.Debug("Target Tenancy OCID: " + xtenancy)
 			tenancyocid = xtenancy
 		} else {
 			// Retrieve the tenancy OCID from the configuration
@@ -298,6 +381,190 @@ func (o *OCIDatasource) GetSubscribedRegions(ctx context.Context, tenancyOCID st
 	return subscribedRegions
 }
 
+// GetCompartments returns the active compartments of a tenancy, including its root compartment, for the
+// compartments() template variable query. Nested compartments are named after their path from the root
+// compartment, e.g. "prod/network", since compartment names are only unique within their parent.
+// API Operation: ListCompartments
+// Permission Required: COMPARTMENT_INSPECT
+//
+// Parameters:
+//   - ctx: The context.Context for the request.
+//   - tenancyOCID: The OCID, or the name in multitenancy mode, of the tenancy.
+//
+// Returns:
+//   - []models.OCIResource: The root compartment, then the other compartments sorted by path.
+//   - error: An error if the tenancy is invalid or the compartments cannot be listed.
+func (o *OCIDatasource) GetCompartments(ctx context.Context, tenancyOCID string) ([]models.OCIResource, error) {
+	takey := o.GetTenancyAccessKey(tenancyOCID)
+	if len(takey) == 0 {
+		return nil, errors.New("invalid tenancy: " + tenancyOCID)
+	}
+	tenancyocid, err := o.FetchTenancyOCID(takey)
+	if err != nil {
+		return nil, err
+	}
+	ta := o.tenancyAccess[takey]
+
+	// The name of the root compartment is the name of the tenancy
+	rootName := takey
+	if tenancy, err := ta.identityClient.GetTenancy(ctx, identity.GetTenancyRequest{TenancyId: common.String(tenancyocid)}); err == nil && tenancy.Name != nil {
+		rootName = *tenancy.Name
+	}
+
+	parents := map[string]string{}
+	names := map[string]string{tenancyocid: rootName}
+	request := identity.ListCompartmentsRequest{
+		CompartmentId:          common.String(tenancyocid),
+		CompartmentIdInSubtree: common.Bool(true),
+		AccessLevel:            identity.ListCompartmentsAccessLevelAccessible,
+		LifecycleState:         identity.CompartmentLifecycleStateActive,
+		Limit:                  common.Int(constants.LimitPerPage),
+	}
+	for {
+		resp, err := ta.identityClient.ListCompartments(ctx, request)
+		if err != nil {
+			o.logger.Error("Could not list the compartments", "tenancy", takey, "error", err)
+			return nil, errors.Wrap(err, "could not list the compartments"+permissionHint(err, permissionInspectCompartments))
+		}
+		for _, compartment := range resp.Items {
+			if compartment.Id == nil || compartment.Name == nil {
+				continue
+			}
+			names[*compartment.Id] = *compartment.Name
+			if compartment.CompartmentId != nil {
+				parents[*compartment.Id] = *compartment.CompartmentId
+			}
+		}
+		if resp.OpcNextPage == nil {
+			break
+		}
+		request.Page = resp.OpcNextPage
+	}
+
+	compartments := []models.OCIResource{{Name: rootName, OCID: tenancyocid}}
+	for id := range parents {
+		// The path stops at the root compartment, or at a parent that is not accessible
+		path := names[id]
+		for parent, ok := parents[id]; ok && parent != tenancyocid; parent, ok = parents[parent] {
+			name, known := names[parent]
+			if !known {
+				break
+			}
+			path = name + "/" + path
+		}
+		compartments = append(compartments, models.OCIResource{Name: path, OCID: id})
+	}
+	sort.SliceStable(compartments[1:], func(i, j int) bool {
+		return compartments[i+1].Name < compartments[j+1].Name
+	})
+	return compartments, nil
+}
+
+// GetLogGroups returns the log groups of a compartment, for the loggroups() template variable query.
+// API Operation: ListLogGroups
+// Permission Required: LOG_GROUP_INSPECT
+//
+// Parameters:
+//   - ctx: The context.Context for the request.
+//   - tenancyOCID: The OCID, or the name in multitenancy mode, of the tenancy.
+//   - compartment: The OCID of the compartment, the log groups of the whole tenancy when empty.
+//   - region: The region of the log groups, the region of the tenancy when empty.
+//
+// Returns:
+//   - []models.OCIResource: The log groups sorted by name.
+//   - error: An error if the tenancy is invalid or the log groups cannot be listed.
+func (o *OCIDatasource) GetLogGroups(ctx context.Context, tenancyOCID string, compartment string, region string) ([]models.OCIResource, error) {
+	takey := o.GetTenancyAccessKey(tenancyOCID)
+	if len(takey) == 0 {
+		return nil, errors.New("invalid tenancy: " + tenancyOCID)
+	}
+	request := logging.ListLogGroupsRequest{
+		CompartmentId: common.String(compartment),
+		Limit:         common.Int(constants.LimitPerPage),
+	}
+	if compartment == "" {
+		tenancyocid, err := o.FetchTenancyOCID(takey)
+		if err != nil {
+			return nil, err
+		}
+		request.CompartmentId = common.String(tenancyocid)
+		request.IsCompartmentIdInSubtree = common.Bool(true)
+	}
+
+	client := o.tenancyAccess[takey].loggingManagementClientFor(region)
+	logGroups := []models.OCIResource{}
+	for {
+		resp, err := client.ListLogGroups(ctx, request)
+		if err != nil {
+			o.logger.Error("Could not list the log groups", "tenancy", takey, "compartment", compartment, "region", region, "error", err)
+			return nil, errors.Wrap(err, "could not list the log groups"+permissionHint(err, permissionReadLogGroups))
+		}
+		for _, logGroup := range resp.Items {
+			if logGroup.Id != nil && logGroup.DisplayName != nil {
+				logGroups = append(logGroups, models.OCIResource{Name: *logGroup.DisplayName, OCID: *logGroup.Id})
+			}
+		}
+		if resp.OpcNextPage == nil {
+			break
+		}
+		request.Page = resp.OpcNextPage
+	}
+
+	sort.SliceStable(logGroups, func(i, j int) bool {
+		return logGroups[i].Name < logGroups[j].Name
+	})
+	return logGroups, nil
+}
+
+// GetLogGroupLogs returns the active logs of a log group, for the logs() template variable query.
+// API Operation: ListLogs
+// Permission Required: LOG_GROUP_INSPECT
+//
+// Parameters:
+//   - ctx: The context.Context for the request.
+//   - tenancyOCID: The OCID, or the name in multitenancy mode, of the tenancy.
+//   - logGroup: The OCID of the log group.
+//   - region: The region of the log group, the region of the tenancy when empty.
+//
+// Returns:
+//   - []models.OCIResource: The logs sorted by name.
+//   - error: An error if the tenancy is invalid or the logs cannot be listed.
+func (o *OCIDatasource) GetLogGroupLogs(ctx context.Context, tenancyOCID string, logGroup string, region string) ([]models.OCIResource, error) {
+	takey := o.GetTenancyAccessKey(tenancyOCID)
+	if len(takey) == 0 {
+		return nil, errors.New("invalid tenancy: " + tenancyOCID)
+	}
+	request := logging.ListLogsRequest{
+		LogGroupId:     common.String(logGroup),
+		LifecycleState: logging.ListLogsLifecycleStateActive,
+		Limit:          common.Int(constants.LimitPerPage),
+	}
+
+	client := o.tenancyAccess[takey].loggingManagementClientFor(region)
+	logs := []models.OCIResource{}
+	for {
+		resp, err := client.ListLogs(ctx, request)
+		if err != nil {
+			o.logger.Error("Could not list the logs", "tenancy", takey, "logGroup", logGroup, "region", region, "error", err)
+			return nil, errors.Wrap(err, "could not list the logs"+permissionHint(err, permissionReadLogGroups))
+		}
+		for _, logSummary := range resp.Items {
+			if logSummary.Id != nil && logSummary.DisplayName != nil {
+				logs = append(logs, models.OCIResource{Name: *logSummary.DisplayName, OCID: *logSummary.Id})
+			}
+		}
+		if resp.OpcNextPage == nil {
+			break
+		}
+		request.Page = resp.OpcNextPage
+	}
+
+	sort.SliceStable(logs, func(i, j int) bool {
+		return logs[i].Name < logs[j].Name
+	})
+	return logs, nil
+}
+
 // identifyQueryType classifies a given OCI Logging search query into a specific query type.
 //
 // This function analyzes the structure of the provided logging query string to determine
@@ -404,8 +671,8 @@ func (o *OCIDatasource) processLogMetricTimeSeries(ctx context.Context,
 	// Populate a SearchLogsDetails structure to provide with the logging search API call
 	req1 := loggingsearch.SearchLogsDetails{}
 
-	// hardcoded for now
-	req1.IsReturnFieldInfo = common.Bool(false)
+	// The field info gives the type of the metric field of the results
+	req1.IsReturnFieldInfo = common.Bool(true)
 
 	// Convert the current to/from time values into the format required for the Logging service search
 	// API call
@@ -429,14 +696,15 @@ func (o *OCIDatasource) processLogMetricTimeSeries(ctx context.Context,
 	// Perform the logs search operation
 	res, err := o.tenancyAccess[takey].loggingSearchClient.SearchLogs(ctx, request)
 	if err != nil {
-		errMessage := fmt.Sprintf("processLogMetricTimeSeries Log search operation FAILED, panelId = %s, refId = %s, err = %s, query = %s", queryPanelId, queryRefId, err, searchQuery)
+		errMessage := fmt.Sprintf("processLogMetricTimeSeries Log search operation FAILED, panelId = %s, refId = %s, err = %s, query = %s%s", queryPanelId, queryRefId, err, searchQuery, permissionHint(err, permissionReadLogContent))
 		o.logger.Error(errMessage)
 		return nil, errors.Wrap(err, errMessage)
 	}
 
 	// Determine how many rows were returned in the search results
 	resultCount := *res.SearchResponse.Summary.ResultCount
-	//*&res.SearchResponse.Results
+	// Values of the metric field that cannot be converted are reported with the results
+	converter := &numericConverter{}
 	if resultCount > 0 {
 
 		// Keep track of the labels to be applied to the field
@@ -485,10 +753,11 @@ func (o *OCIDatasource) processLogMetricTimeSeries(ctx context.Context,
 					aliasIndex := reFuncResultAlias.SubexpIndex("alias")
 
 					numericFieldKey = matches[aliasIndex]
-					numericFieldType = constants.ValueType_Float64
+					numericFieldType = numericFieldValueType(matches[1], "")
 
 					o.logger.Debug("Search query DID match query aggregation function alias regex", "alias", numericFieldKey)
 				}
+				fieldTypes := searchFieldTypes(res.SearchResponse.Fields)
 
 				mLogTimeSeriesResults := make(map[int64]*LogTimeSeriesResult)
 				// Keep track of the unique timestamps encountered so the results timestamp
@@ -547,9 +816,17 @@ func (o *OCIDatasource) processLogMetricTimeSeries(ctx context.Context,
 				// sorted time order
 				sort.Slice(sTimestampKeys, func(i, j int) bool { return sTimestampKeys[i] < sTimestampKeys[j] })
 
+				// When gap filling is requested, make sure there is a timestamp group for every
+				// rounddown bucket of the panel time range, including the buckets for which the
+				// OCI Logging service did not return any row
+				if queryModel.FillMode != constants.FillMode_None {
+					sTimestampKeys = o.addMissingTimestampBuckets(searchQuery, fromMs, toMs, sTimestampKeys, mLogTimeSeriesResults)
+				}
+
 				var fieldDefn *DataFieldElements
 				var timestampResults *LogTimeSeriesResult
 				var searchResultFields map[string]interface{}
+				resultFieldsIdentified := false
 
 				tgtNumRows := len(mLogTimeSeriesResults)
 				// Now that we have the results sorted by time, populate the data field definition
@@ -558,32 +835,29 @@ func (o *OCIDatasource) processLogMetricTimeSeries(ctx context.Context,
 				for rowCount, timestampMs := range sTimestampKeys {
 					timestampResults = mLogTimeSeriesResults[timestampMs]
 
-					if rowCount == 0 {
+					if !resultFieldsIdentified && len(timestampResults.mMetricResults) > 0 {
+						resultFieldsIdentified = true
 						// Loop through the keys for the first log results entry for the associated
 						// timestamp to determine what kind of fields we have in the results
 						for key, value := range *timestampResults.mMetricResults[0] {
 							// Check whether the key contains one of the aggregation functions
 							if key == "count" {
 								numericFieldKey = key
-								// In the JSON content for the log record the count appears as an
-								// integer but when converted becomes a float value
-								numericFieldType = constants.ValueType_Float64
+								// Counts are integers, decoded as float values from the JSON content
+								// and converted back to integers
+								numericFieldType = constants.ValueType_Int
 
 								// If the numeric field key was not already identified from the search
 								// query and the current key contains one of the known query mathematical
 								// functions then this is the numeric field in the log search results
 							} else if numericFieldKey == "" && reFunc.Match([]byte(key)) {
 								numericFieldKey = key
-								// The order of these checks is important since integer fields will likely
-								// be convertible as floating point values
-								if _, ok := value.(int); ok {
-									numericFieldType = constants.ValueType_Int
-								} else if _, ok := value.(float64); ok {
-									numericFieldType = constants.ValueType_Float64
-								} else {
+								// The type of the values depends on the aggregation function, e.g. count
+								// or avg, and on the field info of the results otherwise
+								numericFieldType = numericFieldValueType(reFunc.FindStringSubmatch(key)[1], fieldTypes[key])
+								if numericFieldType == constants.ValueType_Undefined {
 									o.logger.Error("Unable to determine numeric data type for field value",
 										"panelId", queryPanelId, "refId", queryRefId, "value", value)
-									numericFieldType = constants.ValueType_Undefined
 								}
 
 								// If the current key is not for the timestamp or metric field then treat
@@ -597,6 +871,8 @@ func (o *OCIDatasource) processLogMetricTimeSeries(ctx context.Context,
 								sLabelFields = append(sLabelFields, &labelFieldMetadata)
 							}
 						}
+						// Keep the label order stable whatever the order of the keys in the results
+						sort.Slice(sLabelFields, func(i, j int) bool { return sLabelFields[i].LabelName < sLabelFields[j].LabelName })
 					} // end if first row
 
 					// There should always be a timestamp field so go ahead and process that
@@ -613,7 +889,6 @@ func (o *OCIDatasource) processLogMetricTimeSeries(ctx context.Context,
 						// Process the label fields for the log metric to generate a unique key for the
 						// log metric. This logic is the same no matter the data type of the log metric
 						// field
-						metricFieldCombKey := numericFieldKey
 						for _, labelFieldMetadata := range sLabelFields {
 							var labelValueStr string
 							// The label value when provided in the Field data structure is a string so just
@@ -626,8 +901,8 @@ func (o *OCIDatasource) processLogMetricTimeSeries(ctx context.Context,
 								labelValueStr = "null"
 							}
 							labelFieldMetadata.LabelValue = labelValueStr
-							metricFieldCombKey += "_" + labelValueStr
 						}
+						metricFieldCombKey := metricSeriesKey(numericFieldKey, sLabelFields)
 
 						// Process the numeric field in the log search results
 						if numericFieldType == constants.ValueType_Float64 {
@@ -641,28 +916,22 @@ func (o *OCIDatasource) processLogMetricTimeSeries(ctx context.Context,
 							// next call to this function
 							fieldDefn = o.getCreateDataFieldElemsForField(mFieldDefns, tgtNumRows,
 								metricFieldCombKey, "", FieldValueType(constants.ValueType_Float64))
-							if floatValue, ok := searchResultFields[numericFieldKey].(float64); ok {
-								fieldDefn.Values.([]*float64)[rowCount] = &floatValue
-							} else {
-								o.logger.Error("Unable to extract float field value",
-									"panelId", queryPanelId, "refId", queryRefId, "field", numericFieldKey)
-							}
+							converter.field = numericFieldKey
+							fieldDefn.Values.([]*float64)[rowCount] = converter.float64Value(searchResultFields[numericFieldKey])
 
 						} else if numericFieldType == constants.ValueType_Int {
 
 							// Get or create the data field elements structure for this field
 							fieldDefn = o.getCreateDataFieldElemsForField(mFieldDefns, tgtNumRows,
 								metricFieldCombKey, "", FieldValueType(constants.ValueType_Int))
-							if intValue, ok := searchResultFields[numericFieldKey].(int); ok {
-								fieldDefn.Values.([]*int)[rowCount] = &intValue
-							} else {
-								o.logger.Error("Unable to extract int value for ",
-									"panelId", queryPanelId, "refId", queryRefId, "field", numericFieldKey)
-							}
+							converter.field = numericFieldKey
+							fieldDefn.Values.([]*int64)[rowCount] = converter.int64Value(searchResultFields[numericFieldKey])
 
 						} else {
 							o.logger.Error("Encountered unexpected field value type for numeric results logging query",
 								"panelId", queryPanelId, "refId", queryRefId)
+							// Do not attach the labels of this result to the timestamp field
+							continue
 						}
 						// Populate the label values for this log metric
 						for _, labelFieldMetadata := range sLabelFields {
@@ -675,6 +944,11 @@ func (o *OCIDatasource) processLogMetricTimeSeries(ctx context.Context,
 					rowCount++
 				}
 
+				// Replace the values of the buckets without data according to the requested fill mode
+				if queryModel.FillMode != constants.FillMode_None {
+					o.fillTimeSeriesGaps(mFieldDefns, queryModel.FillMode)
+				}
+
 			} else {
 				o.logger.Error("Log search results should NOT contain log records",
 					"panelId", queryPanelId, "refId", queryRefId)
@@ -688,7 +962,102 @@ func (o *OCIDatasource) processLogMetricTimeSeries(ctx context.Context,
 			"refId", queryRefId, "resultCount", *res.SearchResponse.Summary.ResultCount)
 	}
 
-	return mFieldDefns, nil
+	return mFieldDefns, converter.result(nil)
+}
+
+// addMissingTimestampBuckets adds an empty timestamp group for each rounddown bucket of the
+// query time range that has no results, so that every series has a point in every bucket.
+// The added buckets are aligned on the first returned bucket.
+//
+// Parameters:
+//   - searchQuery: The logging search query, used to determine the rounddown interval.
+//   - fromMs: The start time in milliseconds since Unix epoch.
+//   - toMs: The end time in milliseconds since Unix epoch.
+//   - sTimestampKeys: The sorted timestamps returned by the log search.
+//   - mLogTimeSeriesResults: The results timestamp group map, updated in place.
+//
+// Returns:
+//   - The sorted timestamps including the added buckets. The provided timestamps are returned
+//     unchanged if the rounddown interval cannot be determined or too many buckets would be generated.
+func (o *OCIDatasource) addMissingTimestampBuckets(searchQuery string, fromMs int64, toMs int64,
+	sTimestampKeys []int64, mLogTimeSeriesResults map[int64]*LogTimeSeriesResult) []int64 {
+
+	intervalMs, err := rounddownIntervalMs(searchQuery)
+	if err != nil {
+		o.logger.Warn("Unable to determine rounddown interval, gaps will not be filled", "error", err)
+		return sTimestampKeys
+	}
+	if (toMs-fromMs)/intervalMs > constants.MaxFillDataPoints {
+		o.logger.Warn("Too many rounddown buckets for the time range, gaps will not be filled",
+			"intervalMs", intervalMs, "from", fromMs, "to", toMs)
+		return sTimestampKeys
+	}
+
+	// The buckets of rounddown() are not necessarily aligned on multiples of the interval since the
+	// Unix epoch, e.g. for weeks or in the time zone of the tenancy, so align them on the first
+	// returned bucket and start from the bucket which contains the start of the time range
+	var phaseMs int64
+	if len(sTimestampKeys) > 0 {
+		phaseMs = sTimestampKeys[0]
+	}
+	offsetMs := (fromMs - phaseMs) % intervalMs
+	if offsetMs < 0 {
+		offsetMs += intervalMs
+	}
+	for timestampMs := fromMs - offsetMs; timestampMs <= toMs; timestampMs += intervalMs {
+		if _, ok := mLogTimeSeriesResults[timestampMs]; !ok {
+			mLogTimeSeriesResults[timestampMs] = &LogTimeSeriesResult{
+				TimestampMs:    timestampMs,
+				mMetricResults: make([]*map[string]interface{}, 0),
+			}
+			sTimestampKeys = append(sTimestampKeys, timestampMs)
+		}
+	}
+	sort.Slice(sTimestampKeys, func(i, j int) bool { return sTimestampKeys[i] < sTimestampKeys[j] })
+
+	return sTimestampKeys
+}
+
+// fillTimeSeriesGaps replaces the missing values of the numeric fields according to the fill mode:
+// "zero" sets them to zero, "previous" repeats the last known value of the series and "null"
+// leaves them empty.
+//
+// Parameters:
+//   - mFieldDefns: The field definitions of the time series, updated in place.
+//   - fillMode: The fill mode requested with the query.
+func (o *OCIDatasource) fillTimeSeriesGaps(mFieldDefns map[string]*DataFieldElements, fillMode string) {
+	if fillMode != constants.FillMode_Zero && fillMode != constants.FillMode_Previous {
+		return
+	}
+
+	for _, fieldDefn := range mFieldDefns {
+		switch values := fieldDefn.Values.(type) {
+		case []*float64:
+			var previous *float64
+			for i := range values {
+				if values[i] != nil {
+					previous = values[i]
+				} else if fillMode == constants.FillMode_Zero {
+					values[i] = new(float64)
+				} else if previous != nil {
+					value := *previous
+					values[i] = &value
+				}
+			}
+		case []*int64:
+			var previous *int64
+			for i := range values {
+				if values[i] != nil {
+					previous = values[i]
+				} else if fillMode == constants.FillMode_Zero {
+					values[i] = new(int64)
+				} else if previous != nil {
+					value := *previous
+					values[i] = &value
+				}
+			}
+		}
+	}
 }
 
 /*
@@ -762,8 +1131,8 @@ func (o *OCIDatasource) processLogMetrics(ctx context.Context,
 	// Populate a SearchLogsDetails structure to provide with the logging search API call
 	req1 := loggingsearch.SearchLogsDetails{}
 
-	// hardcoded for now
-	req1.IsReturnFieldInfo = common.Bool(false)
+	// The field info gives the type of the metric field of the results
+	req1.IsReturnFieldInfo = common.Bool(true)
 
 	// To fill the data panel from the start of the specified period to the end there needs to be
 	// an initial data point at the start of the period. To be able get this initial data sample
@@ -777,6 +1146,7 @@ func (o *OCIDatasource) processLogMetrics(ctx context.Context,
 
 	numericFieldKey := ""
 	numericFieldType := constants.ValueType_Undefined
+	resultFieldsIdentified := false
 
 	// If the metric generated by the search query is aliased in the logging search
 	// query, e.g.
@@ -793,15 +1163,23 @@ func (o *OCIDatasource) processLogMetrics(ctx context.Context,
 		aliasIndex := reFuncResultAlias.SubexpIndex("alias")
 
 		numericFieldKey = matches[aliasIndex]
-		numericFieldType = constants.ValueType_Float64
+		numericFieldType = numericFieldValueType(matches[1], "")
 		o.logger.Error("Search query DID match query aggregation function alias regex", "alias", numericFieldKey)
 	}
+	// Values of the metric field that cannot be converted are reported with the results
+	converter := &numericConverter{}
 
 	// For the number of required data points loop through the logic to run the query for a sub-interval
 	// of the specified query time range. Process each search query's results and combine all of the results
 	// into a set of data field definitions and set of values per data field. This information will be used
 	// to construct the data frame to be passed to the front end as the response to the incoming query.
 	for intervalCnt := 0; intervalCnt < int(numDataPoints); intervalCnt++ {
+		// Stop between intervals once the query is cancelled or hits its deadline
+		if ctx.Err() != nil {
+			return mFieldDefns, converter.result(interruptedSearch(ctx, intervalCnt,
+				fmt.Sprintf("after interval %d of %d", intervalCnt, numDataPoints)))
+		}
+
 		// Compute the from/to time for the current interval (in milliseconds) if this is not the
 		// initial interval
 		if intervalCnt > 0 {
@@ -831,8 +1209,8 @@ func (o *OCIDatasource) processLogMetrics(ctx context.Context,
 			"interval", intervalCnt, "from", start, "to", end)
 
 		// Set the current query time range start and end times for the current interval
-		req1.TimeStart = &common.SDKTime{start}
-		req1.TimeEnd = &common.SDKTime{end}
+		req1.TimeStart = &common.SDKTime{Time: start}
+		req1.TimeEnd = &common.SDKTime{Time: end}
 		// Directly use the query provided by the user
 		req1.SearchQuery = common.String(searchQuery)
 
@@ -844,9 +1222,19 @@ func (o *OCIDatasource) processLogMetrics(ctx context.Context,
 
 		// Perform the logs search operation
 		res, err := o.tenancyAccess[takey].loggingSearchClient.SearchLogs(ctx, request)
+		if err != nil && ctx.Err() != nil {
+			// The query was cancelled or hit its deadline while the interval was searched
+			return mFieldDefns, converter.result(interruptedSearch(ctx, intervalCnt,
+				fmt.Sprintf("while searching interval %d of %d", intervalCnt+1, numDataPoints)))
+		}
 		if err != nil {
-			errMessage := fmt.Sprintf("processLogMetrics Log search operation FAILED, panelId = %s, refId = %s, err = %s, query = %s", queryPanelId, queryRefId, err, searchQuery)
+			errMessage := fmt.Sprintf("processLogMetrics Log search operation FAILED, panelId = %s, refId = %s, err = %s, query = %s%s", queryPanelId, queryRefId, err, searchQuery, permissionHint(err, permissionReadLogContent))
 			o.logger.Error(errMessage)
+			// Keep the data points of the previous intervals when the datasource returns partial results
+			if partialErr := o.failedSearch(err, intervalCnt, fmt.Sprintf("interval %d of %d", intervalCnt+1, numDataPoints),
+				fmt.Sprintf(", %d intervals returned", intervalCnt)); partialErr != nil {
+				return mFieldDefns, converter.result(partialErr)
+			}
 			return nil, errors.Wrap(err, errMessage)
 		}
 		o.logger.Debug("Log search operation SUCCEEDED", "panelId", queryPanelId, "refId", queryRefId,
@@ -883,12 +1271,13 @@ func (o *OCIDatasource) processLogMetrics(ctx context.Context,
 					for rowCount, logSearchResult := range res.SearchResponse.Results {
 						searchResultData, ok := (*logSearchResult.Data).(map[string]interface{})
 						if ok {
-							// If this is the first row for the first interval then inspect the
-							// values of the elements to speed up the processing of the remaining rows
+							// If this is the first row of the first interval with results then inspect
+							// the values of the elements to speed up the processing of the remaining rows
 							// for all intervals. It is important to do this only for the first row of
 							// all of the results otherwise the order of the label keys may be different
 							// between the search results for different intervals
-							if intervalCnt == 0 && rowCount == 0 {
+							if !resultFieldsIdentified {
+								resultFieldsIdentified = true
 								// Loop through the keys for the entries in the results data item
 								// to determine what kind of fields we have in the results
 								for key, value := range searchResultData {
@@ -896,21 +1285,18 @@ func (o *OCIDatasource) processLogMetrics(ctx context.Context,
 									// Check whether the key contains one of the aggregation functions
 									if key == "count" {
 										numericFieldKey = key
-										// In the JSON content for the log record the count appears as an
-										// integer but when converted becomes a float value
-										numericFieldType = constants.ValueType_Float64
+										// Counts are integers, decoded as float values from the JSON content
+										// and converted back to integers
+										numericFieldType = constants.ValueType_Int
 									} else if numericFieldKey == "" && reFunc.Match([]byte(key)) {
 										numericFieldKey = key
-										// The order of these checks is important since integer fields will likely
-										// be convertible as floating point values
-										if _, ok := value.(int); ok {
-											numericFieldType = constants.ValueType_Int
-										} else if _, ok := value.(float64); ok {
-											numericFieldType = constants.ValueType_Float64
-										} else {
+										// The type of the values depends on the aggregation function, e.g. count
+										// or avg, and on the field info of the results otherwise
+										numericFieldType = numericFieldValueType(reFunc.FindStringSubmatch(key)[1],
+											searchFieldTypes(res.SearchResponse.Fields)[key])
+										if numericFieldType == constants.ValueType_Undefined {
 											o.logger.Error("Unable to determine numeric data type for field value",
 												"panelId", queryPanelId, "refId", queryRefId, "value", value)
-											numericFieldType = constants.ValueType_Undefined
 										}
 									} else if key != numericFieldKey {
 										// Save the information about the label field
@@ -921,12 +1307,13 @@ func (o *OCIDatasource) processLogMetrics(ctx context.Context,
 										sLabelFields = append(sLabelFields, &labelFieldMetadata)
 									}
 								}
+								// Keep the label order stable whatever the order of the keys in the results
+								sort.Slice(sLabelFields, func(i, j int) bool { return sLabelFields[i].LabelName < sLabelFields[j].LabelName })
 							} // end if first row
 
 							// Process the label fields for the log metric to generate a unique key for the
 							// log metric. This logic is the same no matter the data type of the log metric
 							// field
-							metricFieldCombKey := numericFieldKey
 							for _, labelFieldMetadata := range sLabelFields {
 								var labelValueStr string
 								// The label value when provided in the Field data structure is a string so just
@@ -939,8 +1326,8 @@ func (o *OCIDatasource) processLogMetrics(ctx context.Context,
 									labelValueStr = "null"
 								}
 								labelFieldMetadata.LabelValue = labelValueStr
-								metricFieldCombKey += "_" + labelValueStr
 							}
+							metricFieldCombKey := metricSeriesKey(numericFieldKey, sLabelFields)
 
 							// Process the numeric field in the log search results
 							if numericFieldType == constants.ValueType_Float64 {
@@ -955,12 +1342,8 @@ func (o *OCIDatasource) processLogMetrics(ctx context.Context,
 								fieldDefn = o.getCreateDataFieldElemsForField(mFieldDefns, int(numDataPoints),
 									metricFieldCombKey, "", FieldValueType(constants.ValueType_Float64))
 
-								if floatValue, ok := searchResultData[numericFieldKey].(float64); ok {
-									fieldDefn.Values.([]*float64)[intervalCnt] = &floatValue
-								} else {
-									o.logger.Error("Unable to extract float field value",
-										"panelId", queryPanelId, "refId", queryRefId, "field", numericFieldKey)
-								}
+								converter.field = numericFieldKey
+								fieldDefn.Values.([]*float64)[intervalCnt] = converter.float64Value(searchResultData[numericFieldKey])
 
 							} else if numericFieldType == constants.ValueType_Int {
 
@@ -968,16 +1351,14 @@ func (o *OCIDatasource) processLogMetrics(ctx context.Context,
 								fieldDefn = o.getCreateDataFieldElemsForField(mFieldDefns, int(numDataPoints),
 									metricFieldCombKey, "", FieldValueType(constants.ValueType_Int))
 
-								if intValue, ok := searchResultData[numericFieldKey].(int); ok {
-									fieldDefn.Values.([]*int)[intervalCnt] = &intValue
-								} else {
-									o.logger.Error("Unable to extract int value for ",
-										"panelId", queryPanelId, "refId", queryRefId, "field", numericFieldKey)
-								}
+								converter.field = numericFieldKey
+								fieldDefn.Values.([]*int64)[intervalCnt] = converter.int64Value(searchResultData[numericFieldKey])
 
 							} else {
 								o.logger.Debug("Encountered unexpected field value type for numeric results logging query",
 									"panelId", queryPanelId, "refId", queryRefId)
+								// Do not attach the labels of this result to the timestamp field
+								continue
 							}
 
 							// Populate the label values for this log metric
@@ -1008,7 +1389,7 @@ func (o *OCIDatasource) processLogMetrics(ctx context.Context,
 
 	} // end for the required number of data intervals
 
-	return mFieldDefns, nil
+	return mFieldDefns, converter.result(nil)
 }
 
 // processLogRecords retrieves and processes log records from OCI Logging service based on the provided query parameters.
@@ -1030,6 +1411,8 @@ func (o *OCIDatasource) processLogMetrics(ctx context.Context,
 // - Converts the provided time range into the required OCI format.
 // - Constructs and executes a SearchLogs API request.
 // - Iterates through paginated results, extracting relevant log fields.
+// - Sorts the records by time, newest first by default, when the query does not sort them.
+// - Splits time ranges longer than the search chunk size into chunks searched in parallel, see processChunkedLogRecords.
 // - Processes special fields like timestamps separately.
 // - Logs debug and error messages for tracking query execution and potential issues.
 func (o *OCIDatasource) processLogRecords(ctx context.Context,
@@ -1040,8 +1423,10 @@ func (o *OCIDatasource) processLogRecords(ctx context.Context,
 	var queryPanelId string = searchLogsReq.PanelId
 	var numpage = 1
 	var indexCountPag = 0
-	// Implicit assumption that the request contains this field, must be set by the plugin frontend
-	searchQuery := queryModel.QueryText
+	// Implicit assumption that the request contains this field, must be set by the plugin frontend.
+	// The records are sorted by time when the query does not sort them, so that the records kept
+	// when the results exceed the row cap are the most recent ones
+	searchQuery := withSortClause(queryModel.QueryText, queryModel.SortDirection)
 	// Populate a SearchLogsDetails structure to provide with the logging search API call
 	req1 := loggingsearch.SearchLogsDetails{}
 
@@ -1056,13 +1441,23 @@ func (o *OCIDatasource) processLogRecords(ctx context.Context,
 	end = end.Truncate(time.Millisecond)
 
 	// Set the current query time range start and end times for the current interval
-	req1.TimeStart = &common.SDKTime{start}
-	req1.TimeEnd = &common.SDKTime{end}
+	req1.TimeStart = &common.SDKTime{Time: start}
+	req1.TimeEnd = &common.SDKTime{Time: end}
 	// Directly use the query provided by the user
 	req1.SearchQuery = common.String(searchQuery)
 	o.logger.Debug("Processing log records search query", "panelId", queryPanelId, "refId", queryRefId,
 		"query", searchQuery, "from", query.TimeRange.From, "to", query.TimeRange.To)
 
+	// Long time ranges are searched in chunks in parallel, when the records can be stitched in time order.
+	// The decision is made on the query with its sort command, so that a query without sort command is
+	// searched in chunks in the order of the sort direction, newest first by default, and a long time range
+	// only searches the chunks needed to fill the row cap
+	if chunkable, descending := chunkableQuery(searchQuery); chunkable {
+		if chunks := splitSearchRange(fromMs, toMs, o.searchChunkSize(), descending); len(chunks) > 0 {
+			return o.processChunkedLogRecords(ctx, queryPanelId, queryRefId, searchQuery, chunks, mFieldDefns, takey)
+		}
+	}
+
 	// Construct the Logging service SearchLogs request structure
 	request := loggingsearch.SearchLogsRequest{
 		SearchLogsDetails: req1,
@@ -1071,9 +1466,21 @@ func (o *OCIDatasource) processLogRecords(ctx context.Context,
 
 	// Perform the logs search operation
 	for res, err := o.tenancyAccess[takey].loggingSearchClient.SearchLogs(ctx, request); ; res, err = o.tenancyAccess[takey].loggingSearchClient.SearchLogs(ctx, request) {
+		if err != nil && ctx.Err() != nil {
+			// The query was cancelled or hit its deadline while the page was fetched
+			trimFieldValues(mFieldDefns, indexCountPag)
+			return mFieldDefns, interruptedSearch(ctx, numpage-1,
+				fmt.Sprintf("while fetching page %d, %d log records returned", numpage, indexCountPag))
+		}
 		if err != nil {
-			errMessage := fmt.Sprintf("processLogRecords Log search operation FAILED, panelId = %s, refId = %s, err = %s, query = %s", queryPanelId, queryRefId, err, searchQuery)
+			errMessage := fmt.Sprintf("processLogRecords Log search operation FAILED, panelId = %s, refId = %s, err = %s, query = %s%s", queryPanelId, queryRefId, err, searchQuery, permissionHint(err, permissionReadLogContent))
 			o.logger.Error(errMessage)
+			// Keep the log records of the previous pages when the datasource returns partial results
+			if partialErr := o.failedSearch(err, numpage-1, fmt.Sprintf("page %d", numpage),
+				fmt.Sprintf(", %d log records returned", indexCountPag)); partialErr != nil {
+				trimFieldValues(mFieldDefns, indexCountPag)
+				return mFieldDefns, partialErr
+			}
 			return nil, errors.Wrap(err, errMessage)
 		}
 		o.logger.Debug("Log search operation SUCCEEDED", "panelId", queryPanelId, "refId", queryRefId)
@@ -1084,71 +1491,12 @@ func (o *OCIDatasource) processLogRecords(ctx context.Context,
 		if resultCount > 0 {
 			// Loop through each row of the results and add data values for each of encountered fields
 			for rowCount, logSearchResult := range res.SearchResponse.Results {
-				var fieldDefn *DataFieldElements
 				searchResultData, ok := (*logSearchResult.Data).(map[string]interface{})
 				if ok {
 					if logContent, ok := searchResultData[constants.LogSearchResultsField_LogContent]; ok {
 						mLogContent, ok := logContent.(map[string]interface{})
 						if ok {
-							for key, value := range mLogContent {
-
-								// Only three special case fields within a log record: 1) time, 2) data, and 3) oracle
-								// Treat all other logContent fields as strings
-								if key == constants.LogSearchResultsField_Time {
-									fieldDefn = o.getCreateDataFieldElemsForField(mFieldDefns, numMaxResults,
-										constants.LogSearchResponseField_timestamp, constants.LogSearchResponseField_timestamp,
-										FieldValueType(constants.ValueType_Time))
-									timestamp, errStr := time.Parse(time.RFC3339, value.(string))
-									if errStr != nil {
-										o.logger.Debug("Error parsing timestamp string", "panelId", queryPanelId,
-											"refId", queryRefId, constants.LogSearchResponseField_timestamp,
-											mLogContent[constants.LogSearchResultsField_Time],
-											"error", errStr)
-									}
-									fieldDefn.Values.([]*time.Time)[indexCountPag] = &timestamp
-								} else if key == constants.LogSearchResultsField_Data || key == constants.LogSearchResultsField_Oracle {
-									var logData string = ""
-									fieldDefn = o.getCreateDataFieldElemsForField(mFieldDefns, numMaxResults,
-										key, key, FieldValueType(constants.ValueType_String))
-
-									logJSON, marerr := json.Marshal(value)
-									if marerr == nil {
-										logData = string(logJSON)
-									} else {
-										o.logger.Debug("Error marshalling log record data string, log data variable type",
-											"panelId", queryPanelId, "refId", queryRefId, "type", fmt.Sprintf("%T", value))
-										logData = "UNKNOWN"
-									}
-									fieldDefn.Values.([]*string)[indexCountPag] = &logData
-
-									// Skip the subject field since it seems to always be an empty string
-									// For all other keys treat them generically as string type
-								} else if key != constants.LogSearchResultsField_Subject {
-									var stringFieldValue string
-									fieldDefn = nil
-
-									if stringFieldValue, ok = value.(string); ok {
-										// If the field value is non-zero length string then proceed to get/create the data
-										// field definition. But if the field value is a zero length string then skip
-										// creating the data field definition, this is to avoid creating a data field for a
-										// log record field that is always empty.
-										if len(stringFieldValue) > 0 {
-											fieldDefn = o.getCreateDataFieldElemsForField(mFieldDefns, numMaxResults,
-												key, key, FieldValueType(constants.ValueType_String))
-										}
-									} else {
-										o.logger.Debug("Error parsing string field value", "panelId", queryPanelId,
-											"refId", queryRefId, "key", key, "value", value)
-										fieldDefn = o.getCreateDataFieldElemsForField(mFieldDefns, numMaxResults,
-											key, key, FieldValueType(constants.ValueType_String))
-										stringFieldValue = "UNKNOWN"
-									}
-									if fieldDefn != nil {
-										fieldDefn.Values.([]*string)[indexCountPag] = &stringFieldValue
-									}
-								} // endif key name
-							} // for each field key in the logContent field
-
+							o.setLogRecordFields(mFieldDefns, mLogContent, indexCountPag, queryPanelId, queryRefId)
 						} else {
 							o.logger.Debug("Unable to get logContent map", "panelId", queryPanelId,
 								"refId", queryRefId, "row", rowCount)
@@ -1169,28 +1517,18 @@ func (o *OCIDatasource) processLogRecords(ctx context.Context,
 				"refId", queryRefId)
 		}
 		if res.OpcNextPage != nil && numpage < MaxPagesToFetch {
+			// Stop between pages once the query is cancelled or hits its deadline
+			if ctx.Err() != nil {
+				trimFieldValues(mFieldDefns, indexCountPag)
+				return mFieldDefns, interruptedSearch(ctx, numpage,
+					fmt.Sprintf("after page %d, %d log records returned", numpage, indexCountPag))
+			}
 			// if there are more items in next page, fetch items from next page
 			request.Page = res.OpcNextPage
 			numpage++
 		} else {
 			o.logger.Debug("Reducing data field values", "resultsCount", indexCountPag)
-			o.logger.Warn("Logging search query PIRLAs", "PIRLA", mFieldDefns)
-
-			for _, dataFieldDefn := range mFieldDefns {
-				if dataFieldDefn.Type == FieldValueType(constants.ValueType_Time) {
-					timeValuesSlice, _ := dataFieldDefn.Values.([]*time.Time)
-					dataFieldDefn.Values = timeValuesSlice[:indexCountPag]
-				} else if dataFieldDefn.Type == FieldValueType(constants.ValueType_Float64) {
-					floatValuesSlice, _ := dataFieldDefn.Values.([]*float64)
-					dataFieldDefn.Values = floatValuesSlice[:indexCountPag]
-				} else if dataFieldDefn.Type == FieldValueType(constants.ValueType_Int) {
-					intValuesSlice, _ := dataFieldDefn.Values.([]*int)
-					dataFieldDefn.Values = intValuesSlice[:indexCountPag]
-				} else { // Treat all other data types as a string (including string fields)
-					stringValuesSlice, _ := dataFieldDefn.Values.([]*string)
-					dataFieldDefn.Values = stringValuesSlice[:indexCountPag]
-				}
-			}
+			trimFieldValues(mFieldDefns, indexCountPag)
 			// no more result, break the loop
 			break
 		}
@@ -1198,35 +1536,123 @@ func (o *OCIDatasource) processLogRecords(ctx context.Context,
 	return mFieldDefns, nil
 }
 
-// getLogs retrieves log records from the OCI Logging service based on the specified query parameters.
+// setLogRecordFields adds the fields of a log record to the data field definitions of a log records query.
+// Only three fields of a log record are handled as special cases: the time, which is parsed as the timestamp,
+// and the data and oracle elements, which are marshalled as JSON strings. All other fields are strings.
+//
+// Parameters:
+//   - mFieldDefns: The data field definitions of the query.
+//   - mLogContent: The logContent element of the log record.
+//   - index: The index of the log record within the results of the query.
+//   - queryPanelId: The panel of the query, for logging.
+//   - queryRefId: The reference ID of the query, for logging.
+func (o *OCIDatasource) setLogRecordFields(mFieldDefns map[string]*DataFieldElements, mLogContent map[string]interface{},
+	index int, queryPanelId string, queryRefId string) {
+	var fieldDefn *DataFieldElements
+	var ok bool
+
+	for key, value := range mLogContent {
+
+		// Only three special case fields within a log record: 1) time, 2) data, and 3) oracle
+		// Treat all other logContent fields as strings
+		if key == constants.LogSearchResultsField_Time {
+			fieldDefn = o.getCreateDataFieldElemsForField(mFieldDefns, numMaxResults,
+				constants.LogSearchResponseField_timestamp, constants.LogSearchResponseField_timestamp,
+				FieldValueType(constants.ValueType_Time))
+			timestamp, errStr := time.Parse(time.RFC3339, value.(string))
+			if errStr != nil {
+				o.logger.Debug("Error parsing timestamp string", "panelId", queryPanelId,
+					"refId", queryRefId, constants.LogSearchResponseField_timestamp,
+					mLogContent[constants.LogSearchResultsField_Time],
+					"error", errStr)
+			}
+			fieldDefn.Values.([]*time.Time)[index] = &timestamp
+		} else if key == constants.LogSearchResultsField_Data || key == constants.LogSearchResultsField_Oracle {
+			var logData string = ""
+			fieldDefn = o.getCreateDataFieldElemsForField(mFieldDefns, numMaxResults,
+				key, key, FieldValueType(constants.ValueType_String))
+
+			logJSON, marerr := json.Marshal(value)
+			if marerr == nil {
+				logData = string(logJSON)
+			} else {
+				o.logger.Debug("Error marshalling log record data string, log data variable type",
+					"panelId", queryPanelId, "refId", queryRefId, "type", fmt.Sprintf("%T", value))
+				logData = "UNKNOWN"
+			}
+			fieldDefn.Values.([]*string)[index] = &logData
+
+			// Skip the subject field since it seems to always be an empty string
+			// For all other keys treat them generically as string type
+		} else if key != constants.LogSearchResultsField_Subject {
+			var stringFieldValue string
+			fieldDefn = nil
+
+			if stringFieldValue, ok = value.(string); ok {
+				// If the field value is non-zero length string then proceed to get/create the data
+				// field definition. But if the field value is a zero length string then skip
+				// creating the data field definition, this is to avoid creating a data field for a
+				// log record field that is always empty.
+				if len(stringFieldValue) > 0 {
+					fieldDefn = o.getCreateDataFieldElemsForField(mFieldDefns, numMaxResults,
+						key, key, FieldValueType(constants.ValueType_String))
+				}
+			} else {
+				o.logger.Debug("Error parsing string field value", "panelId", queryPanelId,
+					"refId", queryRefId, "key", key, "value", value)
+				fieldDefn = o.getCreateDataFieldElemsForField(mFieldDefns, numMaxResults,
+					key, key, FieldValueType(constants.ValueType_String))
+				stringFieldValue = "UNKNOWN"
+			}
+			if fieldDefn != nil {
+				fieldDefn.Values.([]*string)[index] = &stringFieldValue
+			}
+		} // endif key name
+	} // for each field key in the logContent field
+}
+
+// getLogs retrieves the values of a template variable from the log records of a logging search query.
 //
 // Parameters:
 // - ctx (context.Context): The execution context for the API request.
 // - tenancyOCID (string): The OCID of the tenancy from which logs should be fetched.
 // - QueryText (string): The log query string to be used for searching logs.
-// - Field (string): The specific field to extract from the log records.
+// - Field (string): The field to extract from the log records, or "<text field>, <value field>".
+// - Regex (string): The regex of the texts of the values to keep, empty to keep all values.
 // - tstart (int64): The start time for the log search in milliseconds since the Unix epoch (0 for default: last 5 minutes).
 // - tend (int64): The end time for the log search in milliseconds since the Unix epoch (0 for default: current time).
 //
 // Returns:
-// - ([]string, error): A list of unique extracted field values from the log records and an error (if any).
+// - ([]models.VariableValue, error): The text and value pairs extracted from the log records, sorted by text and
+// without duplicates, and an error (if any).
 //
 // The function performs the following steps:
 // - Determines the time range for the query, defaulting to the last 5 minutes if no start time is provided.
 // - Constructs and executes a SearchLogs API request.
-// - Iterates through the returned log search results, extracting relevant fields from log data.
-// - Uses `extractField` to extract the specified field from each log record.
-// - Handles errors and logs failures at various stages of processing.
-// - Ensures unique results before returning the final list of extracted field values.
-func (o *OCIDatasource) getLogs(ctx context.Context, tenancyOCID string, QueryText string, Field string, tstart int64, tend int64) ([]string, error) {
+// - Extracts the text and value of each returned log record, see logContentVariableField, or of each
+// aggregated result, see aggregatedVariableField. Records without the value field are skipped.
+// - Sorts, deduplicates and filters the values with the regex.
+func (o *OCIDatasource) getLogs(ctx context.Context, tenancyOCID string, QueryText string, Field string, Regex string,
+	tstart int64, tend int64) ([]models.VariableValue, error) {
 	takey := o.GetTenancyAccessKey(tenancyOCID)
+	if len(takey) == 0 {
+		return nil, errors.New("invalid tenancy: " + tenancyOCID)
+	}
+
+	var filter *regexp.Regexp
+	if Regex != "" {
+		var err error
+		if filter, err = regexp.Compile(Regex); err != nil {
+			return nil, errors.Wrap(err, "invalid regex of the template variable")
+		}
+	}
+	textPath, valuePath := variableFieldPaths(Field)
 
 	var t1 time.Time
 	var t2 time.Time
 
 	if tstart == 0 {
-		t1 = t1.Add(-time.Minute * 5)
-
+		t1 = time.Now().Add(-time.Minute * 5)
 	} else {
 		t1 = time.Unix(tstart/1000, 0)
 	}
@@ -1245,12 +1671,12 @@ func (o *OCIDatasource) getLogs(ctx context.Context, tenancyOCID string, QueryTe
 	req1.IsReturnFieldInfo = common.Bool(false)
 
 	// Set the current query time range start and end times for the current interval
-	req1.TimeStart = &common.SDKTime{start}
-	req1.TimeEnd = &common.SDKTime{end}
+	req1.TimeStart = &common.SDKTime{Time: start}
+	req1.TimeEnd = &common.SDKTime{Time: end}
 	// Directly use the query provided by the user
 	req1.SearchQuery = common.String(QueryText)
 
-	var results []string
+	results := make([]models.VariableValue, 0)
 
 	// Construct the Logging service SearchLogs request structure
 	searchLogsRequest := loggingsearch.SearchLogsRequest{
@@ -1266,75 +1692,219 @@ func (o *OCIDatasource) getLogs(ctx context.Context, tenancyOCID string, QueryTe
 		return nil, errors.Wrap(err, errMessage)
 	}
 
-	status := searchLogsResponse.RawResponse.StatusCode
-	if status <= 200 && status > 300 {
-		o.logger.Error(fmt.Sprintf("Template Var Log search operation FAILED, err = %d", status))
-		return nil, errors.Wrap(err, fmt.Sprintf("Template Var Log search operation FAILED %s %d", spew.Sdump(searchLogsResponse), status))
+	// Loop through each row of the results and extract the text and value of the variable
+	for rowCount, logSearchResult := range searchLogsResponse.SearchResponse.Results {
+		searchResultData, ok := (*logSearchResult.Data).(map[string]interface{})
+		if !ok {
+			o.logger.Debug("Encountered row without search result data", "QueryTemplateVar", rowCount)
+			continue
+		}
+
+		fieldValue := aggregatedVariableField
+		if logContent, ok := searchResultData[constants.LogSearchResultsField_LogContent]; ok {
+			mLogContent, ok := logContent.(map[string]interface{})
+			if !ok {
+				o.logger.Debug("Encountered log record without a logContent element", "QueryTemplateVar", rowCount)
+				continue
+			}
+			searchResultData = mLogContent
+			fieldValue = logContentVariableField
+		}
+
+		value, ok := fieldValue(searchResultData, valuePath)
+		if !ok {
+			o.logger.Debug("Encountered row without the variable field", "QueryTemplateVar", rowCount, "field", valuePath)
+			continue
+		}
+		text := value
+		if textPath != valuePath {
+			if textValue, ok := fieldValue(searchResultData, textPath); ok && textValue != "" {
+				text = textValue
+			}
+		}
+		results = append(results, models.VariableValue{Text: text, Value: value})
 	}
 
-	// Determine how many rows were returned in the search results
-	resultCount := *searchLogsResponse.SearchResponse.Summary.ResultCount
+	return filterVariableValues(results, filter), nil
+}
 
-	if resultCount > 0 {
-		// Loop through each row of the results and add data values for each of encountered fields
-		for _, logSearchResult := range searchLogsResponse.SearchResponse.Results {
-			o.logger.Debug("logSearchResult", "QueryTemplateVar", logSearchResult.Data)
-
-			if searchResultData, ok := (*logSearchResult.Data).(map[string]interface{}); ok {
-
-				if logContent, ok := searchResultData[constants.LogSearchResultsField_LogContent]; ok {
-					o.logger.Debug("logContent: ", "QueryTemplateVar", logContent)
-
-					if mLogContent, ok := logContent.(map[string]interface{}); ok {
-						for key, value := range mLogContent {
-							if key == constants.LogSearchResultsField_Data {
-								var logData string = ""
-								logJSON, marerr := json.Marshal(value)
-								if marerr == nil {
-									logData = string(logJSON)
-								} else {
-									o.logger.Error("Cannot marshal logJson: ", "QueryTemplateVar", err)
-									return nil, err
-								}
+// searchLogRecords runs a logging search query over the given time range and returns the logContent
+// element of each returned log record. Result pages are followed until maxRecords log records have
+// been collected or there are no more pages.
+//
+// Parameters:
+//   - ctx: The context for the request execution.
+//   - takey: The tenancy key for accessing the appropriate OCI client.
+//   - searchQuery: The logging search query.
+//   - start: The start of the time range.
+//   - end: The end of the time range.
+//   - maxRecords: The maximum number of log records to return.
+//
+// Returns:
+//   - The logContent elements of the log records, in the order returned by the OCI Logging service.
+//   - An error if a log search operation fails.
+func (o *OCIDatasource) searchLogRecords(ctx context.Context, takey string, searchQuery string,
+	start time.Time, end time.Time, maxRecords int) ([]map[string]interface{}, error) {
 
-								result, err := extractField(logData, Field)
-								if err != nil {
-									o.logger.Error("Error extracting Field: ", "QueryTemplateVar", err)
-									fmt.Printf("Error: %v\n", err)
-								} else {
-									o.logger.Error("Getting logContent: ", "QueryTemplateVar", result)
-									results = append(results, result, result)
-								}
-							}
-						} // for each field key in the logContent field
+	logRecords := make([]map[string]interface{}, 0)
 
-					} else {
-						o.logger.Error("Unable to get logContent map: ", "QueryTemplateVar", err)
-						return nil, err
-					}
-				} else {
-					result, err := FilterMap(*logSearchResult.Data)
-					if err != nil {
-						o.logger.Error("Error extracting data element: ", "QueryTemplateVar", err)
-						return nil, err
-					} else {
-						o.logger.Error("Getting logContent: ", "QueryTemplateVar", result)
-						results = append(results, result, result)
-					}
-				}
-			} else {
-				o.logger.Error("Log Search Data Result error: ", "QueryTemplateVar", err)
-				return nil, err
+	request := loggingsearch.SearchLogsRequest{
+		SearchLogsDetails: loggingsearch.SearchLogsDetails{
+			SearchQuery:       common.String(searchQuery),
+			TimeStart:         &common.SDKTime{Time: start.UTC().Truncate(time.Millisecond)},
+			TimeEnd:           &common.SDKTime{Time: end.UTC().Truncate(time.Millisecond)},
+			IsReturnFieldInfo: common.Bool(false),
+		},
+	}
+
+	for numpage := 1; numpage <= MaxPagesToFetch && len(logRecords) < maxRecords; numpage++ {
+		// Stop between pages once the request is cancelled or hits its deadline
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		request.Limit = common.Int(min(constants.LimitPerPage, maxRecords-len(logRecords)))
+
+		res, err := o.tenancyAccess[takey].loggingSearchClient.SearchLogs(ctx, request)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("log search operation FAILED, query = %s", searchQuery))
+		}
+
+		for rowCount, logSearchResult := range res.SearchResponse.Results {
+			searchResultData, ok := (*logSearchResult.Data).(map[string]interface{})
+			if !ok {
+				o.logger.Debug("Encountered row without a log record", "row", rowCount)
+				continue
 			}
+			mLogContent, ok := searchResultData[constants.LogSearchResultsField_LogContent].(map[string]interface{})
+			if !ok {
+				o.logger.Debug("Encountered log record without a logContent element", "row", rowCount)
+				continue
+			}
+			logRecords = append(logRecords, mLogContent)
 		}
 
-	} else {
-		o.logger.Error("SearchResponse.Summary.ResultCount is empty: ", "QueryTemplateVar", resultCount)
+		if res.OpcNextPage == nil {
+			break
+		}
+		request.Page = res.OpcNextPage
+	}
+
+	return logRecords, nil
+}
+
+// Matches the OCIDs which can be written into the log search query of a log context request, excluding
+// the characters which would end the quoted scope or the oracle.logid filter
+var reLogContextOCID = regexp.MustCompile(`^ocid1\.[a-z0-9]+\.[^'"/|]+$`)
+
+// validateLogContextRequest checks that the OCIDs of a log context request are valid OCIDs, as they are
+// written into the log search query.
+//
+// Parameters:
+//   - rr: The log context request.
+//
+// Returns:
+//   - An error naming the first invalid OCID.
+func validateLogContextRequest(rr logContextRequest) error {
+	ocids := []struct {
+		name  string
+		value string
+	}{
+		{"log", rr.LogId},
+		{"log group", rr.LogGroupId},
+		{"compartment", rr.CompartmentId},
+	}
+	for _, ocid := range ocids {
+		if ocid.value == "" && ocid.name != "log" {
+			continue
+		}
+		if !reLogContextOCID.MatchString(ocid.value) {
+			return errors.Errorf("invalid %s OCID: %q", ocid.name, ocid.value)
+		}
+	}
+
+	return nil
+}
+
+// getLogContext retrieves the log records written to the same log right before and right after a
+// given log record, for Grafana's "show context" feature. Both searches are bounded in time and scoped
+// to the log of the record.
+//
+// Parameters:
+//   - ctx: The context for the request execution.
+//   - rr: The log context request holding the log OCID and timestamp of the record.
+//
+// Returns:
+//   - The preceding and following log records, in time order.
+//   - An error if the tenancy or an OCID is invalid or a log search operation fails.
+func (o *OCIDatasource) getLogContext(ctx context.Context, rr logContextRequest) (*models.LogContextResult, error) {
+	if err := validateLogContextRequest(rr); err != nil {
+		return nil, err
+	}
+
+	takey := o.GetTenancyAccessKey(rr.Tenancy)
+	if len(takey) == 0 {
+		return nil, errors.New("invalid tenancy: " + rr.Tenancy)
+	}
+
+	limit := rr.Limit
+	if limit <= 0 {
+		limit = constants.DefaultLogContextLines
+	} else if limit > constants.MaxLogContextLines {
+		limit = constants.MaxLogContextLines
+	}
+
+	// Scope the search as narrowly as the provided OCIDs allow, the log OCID filter
+	// guarantees that only the records of the log are returned
+	scope := rr.CompartmentId
+	if scope == "" {
+		tenancyocid, err := o.FetchTenancyOCID(takey)
+		if err != nil {
+			return nil, err
+		}
+		scope = tenancyocid
+	}
+	if rr.CompartmentId != "" && rr.LogGroupId != "" {
+		scope += "/" + rr.LogGroupId + "/" + rr.LogId
+	}
+	searchQuery := fmt.Sprintf(`search "%s" | where oracle.logid = '%s'`, scope, rr.LogId)
+
+	recordTime := time.UnixMilli(rr.Time).UTC()
+	window := time.Duration(constants.LogContextSearchWindow) * time.Second
+	// One more record is requested in each direction to make up for the record itself
+	before, err := o.searchLogRecords(ctx, takey, searchQuery+" | sort by datetime desc",
+		recordTime.Add(-window), recordTime, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	after, err := o.searchLogRecords(ctx, takey, searchQuery+" | sort by datetime asc",
+		recordTime.Add(time.Millisecond), recordTime.Add(window), limit+1)
+	if err != nil {
 		return nil, err
 	}
 
-	// Create a map to store unique entries
-	uniqueEntries := uniqueStrings(results)
+	result := &models.LogContextResult{
+		Before: make([]map[string]interface{}, 0, limit),
+		After:  make([]map[string]interface{}, 0, limit),
+	}
+	for _, logRecord := range before {
+		if len(result.Before) == limit {
+			break
+		}
+		if rr.RecordId != "" && logFieldString(logRecord["id"]) == rr.RecordId {
+			continue
+		}
+		result.Before = append(result.Before, logRecord)
+	}
+	// The preceding records were searched newest first, return them oldest first
+	for i, j := 0, len(result.Before)-1; i < j; i, j = i+1, j-1 {
+		result.Before[i], result.Before[j] = result.Before[j], result.Before[i]
+	}
+	for _, logRecord := range after {
+		if len(result.After) == limit {
+			break
+		}
+		result.After = append(result.After, logRecord)
+	}
 
-	return uniqueEntries, nil
+	return result, nil
 }
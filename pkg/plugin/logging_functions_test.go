@@ -0,0 +1,243 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+
+	"github.com/oracle/oci-grafana-logs/pkg/plugin/constants"
+	"github.com/oracle/oci-grafana-logs/pkg/plugin/models"
+)
+
+func TestAddMissingTimestampBuckets(t *testing.T) {
+	const minute = int64(60 * 1000)
+	tests := []struct {
+		name     string
+		query    string
+		fromMs   int64
+		toMs     int64
+		returned []int64
+		want     []int64
+	}{
+		{
+			name:     "fills the buckets without rows",
+			query:    `search "x" | summarize count() by rounddown(datetime, '5m')`,
+			fromMs:   0,
+			toMs:     20 * minute,
+			returned: []int64{5 * minute, 15 * minute},
+			want:     []int64{0, 5 * minute, 10 * minute, 15 * minute, 20 * minute},
+		},
+		{
+			name:     "aligns the first bucket on the interval",
+			query:    `search "x" | summarize count() by rounddown(datetime, '1h')`,
+			fromMs:   90 * minute,
+			toMs:     200 * minute,
+			returned: []int64{120 * minute},
+			want:     []int64{60 * minute, 120 * minute, 180 * minute},
+		},
+		{
+			name:     "aligns the weeks on the first returned bucket",
+			query:    `search "x" | summarize count() by rounddown(datetime, '1w')`,
+			fromMs:   time.Date(2024, 4, 16, 12, 0, 0, 0, time.UTC).UnixMilli(),
+			toMs:     time.Date(2024, 5, 20, 12, 0, 0, 0, time.UTC).UnixMilli(),
+			returned: []int64{time.Date(2024, 4, 29, 0, 0, 0, 0, time.UTC).UnixMilli()},
+			want: []int64{
+				time.Date(2024, 4, 15, 0, 0, 0, 0, time.UTC).UnixMilli(),
+				time.Date(2024, 4, 22, 0, 0, 0, 0, time.UTC).UnixMilli(),
+				time.Date(2024, 4, 29, 0, 0, 0, 0, time.UTC).UnixMilli(),
+				time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC).UnixMilli(),
+				time.Date(2024, 5, 13, 0, 0, 0, 0, time.UTC).UnixMilli(),
+				time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC).UnixMilli(),
+			},
+		},
+		{
+			name:     "aligns the days on the first returned bucket",
+			query:    `search "x" | summarize count() by rounddown(datetime, '1d')`,
+			fromMs:   time.Date(2024, 5, 1, 2, 0, 0, 0, time.UTC).UnixMilli(),
+			toMs:     time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC).UnixMilli(),
+			returned: []int64{time.Date(2024, 5, 2, 22, 0, 0, 0, time.UTC).UnixMilli()},
+			want: []int64{
+				time.Date(2024, 4, 30, 22, 0, 0, 0, time.UTC).UnixMilli(),
+				time.Date(2024, 5, 1, 22, 0, 0, 0, time.UTC).UnixMilli(),
+				time.Date(2024, 5, 2, 22, 0, 0, 0, time.UTC).UnixMilli(),
+			},
+		},
+		{
+			name:     "keeps the rows without rounddown interval",
+			query:    `search "x" | summarize count()`,
+			fromMs:   0,
+			toMs:     20 * minute,
+			returned: []int64{5 * minute},
+			want:     []int64{5 * minute},
+		},
+		{
+			name:     "keeps the rows when there are too many buckets",
+			query:    `search "x" | summarize count() by rounddown(datetime, '1s')`,
+			fromMs:   0,
+			toMs:     (constants.MaxFillDataPoints + 1) * 1000,
+			returned: []int64{5000},
+			want:     []int64{5000},
+		},
+	}
+
+	o := &OCIDatasource{logger: log.DefaultLogger}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := map[int64]*LogTimeSeriesResult{}
+			for _, timestampMs := range tt.returned {
+				results[timestampMs] = &LogTimeSeriesResult{TimestampMs: timestampMs}
+			}
+
+			got := o.addMissingTimestampBuckets(tt.query, tt.fromMs, tt.toMs, append([]int64{}, tt.returned...), results)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("addMissingTimestampBuckets() = %v, want %v", got, tt.want)
+			}
+			for _, timestampMs := range got {
+				if results[timestampMs] == nil {
+					t.Errorf("no results group for bucket %d", timestampMs)
+				}
+			}
+		})
+	}
+}
+
+func TestFillTimeSeriesGaps(t *testing.T) {
+	value := func(v float64) *float64 { return &v }
+	tests := []struct {
+		fillMode string
+		want     []*float64
+	}{
+		{fillMode: constants.FillMode_Zero, want: []*float64{value(0), value(1), value(0), value(3)}},
+		{fillMode: constants.FillMode_Previous, want: []*float64{nil, value(1), value(1), value(3)}},
+		{fillMode: constants.FillMode_Null, want: []*float64{nil, value(1), nil, value(3)}},
+	}
+
+	o := &OCIDatasource{logger: log.DefaultLogger}
+	for _, tt := range tests {
+		t.Run(tt.fillMode, func(t *testing.T) {
+			fields := map[string]*DataFieldElements{
+				"count": {Name: "count", Type: FieldValueType(constants.ValueType_Float64), Values: []*float64{nil, value(1), nil, value(3)}},
+			}
+			o.fillTimeSeriesGaps(fields, tt.fillMode)
+			if !reflect.DeepEqual(fields["count"].Values, tt.want) {
+				t.Fatalf("fillTimeSeriesGaps(%s) = %v, want %v", tt.fillMode, fields["count"].Values, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateLogContextRequest(t *testing.T) {
+	const logOCID = "ocid1.log.oc1.iad.amaaaaaa"
+	tests := []struct {
+		name    string
+		rr      logContextRequest
+		wantErr bool
+	}{
+		{name: "log only", rr: logContextRequest{LogId: logOCID}},
+		{name: "log, log group and compartment", rr: logContextRequest{LogId: logOCID, LogGroupId: "ocid1.loggroup.oc1.iad.amaaaaaa", CompartmentId: "ocid1.compartment.oc1..amaaaaaa"}},
+		{name: "missing log", rr: logContextRequest{}, wantErr: true},
+		{name: "quote in the log", rr: logContextRequest{LogId: logOCID + "' or '1' = '1"}, wantErr: true},
+		{name: "pipeline in the compartment", rr: logContextRequest{LogId: logOCID, CompartmentId: "ocid1.compartment.oc1..a\" | delete"}, wantErr: true},
+		{name: "path in the log group", rr: logContextRequest{LogId: logOCID, LogGroupId: "ocid1.loggroup.oc1.iad.a/b"}, wantErr: true},
+		{name: "not an OCID", rr: logContextRequest{LogId: "mylog"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateLogContextRequest(tt.rr); (err != nil) != tt.wantErr {
+				t.Fatalf("validateLogContextRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSummarizeTenanciesHealth(t *testing.T) {
+	ok := func(name string) models.TenancyHealth {
+		return models.TenancyHealth{Key: name, Name: name, Healthy: true, Auth: models.HealthCheckOK}
+	}
+	failing := func(name string, errs ...string) models.TenancyHealth {
+		return models.TenancyHealth{Key: name, Name: name, Auth: models.HealthCheckFailed, Errors: errs}
+	}
+
+	tests := []struct {
+		name        string
+		tenancies   []models.TenancyHealth
+		wantHealthy int
+		wantFailing int
+		wantMessage string
+		wantStatus  backend.HealthStatus
+	}{
+		{
+			name:        "single healthy tenancy",
+			tenancies:   []models.TenancyHealth{ok("DEFAULT")},
+			wantHealthy: 1,
+			wantMessage: "Success",
+			wantStatus:  backend.HealthStatusOk,
+		},
+		{
+			name:        "all healthy",
+			tenancies:   []models.TenancyHealth{ok("a"), ok("b")},
+			wantHealthy: 2,
+			wantMessage: "Success: 2 tenancies healthy: a, b",
+			wantStatus:  backend.HealthStatusOk,
+		},
+		{
+			name:        "mixed",
+			tenancies:   []models.TenancyHealth{ok("a"), failing("b", "authentication failed", "log search not permitted")},
+			wantHealthy: 1,
+			wantFailing: 1,
+			wantMessage: "1 tenancies healthy: a. 1 tenancies failing: b (authentication failed; log search not permitted)",
+			wantStatus:  backend.HealthStatusError,
+		},
+		{
+			name:        "all failing",
+			tenancies:   []models.TenancyHealth{failing("a", "region us-ashburn-1 unreachable"), failing("b", "authentication failed")},
+			wantFailing: 2,
+			wantMessage: "All 2 tenancies failing: a (region us-ashburn-1 unreachable), b (authentication failed)",
+			wantStatus:  backend.HealthStatusError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := summarizeTenanciesHealth(tt.tenancies)
+			if report.Healthy != tt.wantHealthy || report.Failing != tt.wantFailing {
+				t.Fatalf("summarizeTenanciesHealth() = %d healthy, %d failing, want %d, %d", report.Healthy, report.Failing, tt.wantHealthy, tt.wantFailing)
+			}
+			if report.Message != tt.wantMessage {
+				t.Fatalf("summarizeTenanciesHealth() message = %q, want %q", report.Message, tt.wantMessage)
+			}
+
+			result := healthCheckResult(report)
+			if result.Status != tt.wantStatus || result.Message != tt.wantMessage {
+				t.Fatalf("healthCheckResult() = %v %q, want %v %q", result.Status, result.Message, tt.wantStatus, tt.wantMessage)
+			}
+			var details struct {
+				Tenancies []models.TenancyHealth `json:"tenancies"`
+			}
+			if err := json.Unmarshal(result.JSONDetails, &details); err != nil {
+				t.Fatalf("healthCheckResult() details are not JSON: %v", err)
+			}
+			if !reflect.DeepEqual(details.Tenancies, tt.tenancies) {
+				t.Fatalf("healthCheckResult() details = %+v, want %+v", details.Tenancies, tt.tenancies)
+			}
+		})
+	}
+}
+
+func TestCheckTenanciesHealthWithoutTenancy(t *testing.T) {
+	o := &OCIDatasource{logger: log.DefaultLogger, tenancyAccess: map[string]*logTenancyAccess{}}
+	if _, err := o.CheckTenanciesHealth(context.Background()); err == nil {
+		t.Fatalf("CheckTenanciesHealth() error = nil, want an error when no tenancy is configured")
+	}
+}
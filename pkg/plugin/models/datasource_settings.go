@@ -7,6 +7,8 @@ package models
 
 import (
 	"fmt"
+	"net/url"
+	"strings"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	jsoniter "github.com/json-iterator/go"
@@ -14,14 +16,64 @@ import (
 	"github.com/oracle/oci-grafana-logs/pkg/plugin/constants"
 )
 
+// ProfileSettings holds the non secured settings of a user principal profile.
+// The secured settings of the profile at index i of the profile list are stored in the
+// secure JSON data with the i suffix: tenancy<i>, user<i>, fingerprint<i>, privkey<i>, privkeypass<i> and customdomain<i>.
+type ProfileSettings struct {
+	Name         string `json:"name"`
+	Region       string `json:"region,omitempty"`
+	Dedicated    bool   `json:"dedicated,omitempty"`
+	CustomRegion string `json:"customRegion,omitempty"`
+
+	Endpoints EndpointSettings `json:"endpoints,omitempty"`
+}
+
+// EndpointSettings holds explicit endpoints of the OCI services, overriding the endpoints resolved
+// from the region and realm. An empty endpoint is resolved from the region.
+type EndpointSettings struct {
+	LoggingSearch     string `json:"loggingSearch,omitempty"`
+	LoggingManagement string `json:"loggingManagement,omitempty"`
+	Identity          string `json:"identity,omitempty"`
+}
+
+// CrossTenancySettings holds a target tenancy that an instance principal reads through cross-tenancy policies.
+type CrossTenancySettings struct {
+	Name        string `json:"name"`
+	TenancyOCID string `json:"tenancy"`
+}
+
 // OCIDatasourceSettings holds the datasource configuration information for OCI
 type OCIDatasourceSettings struct {
-	AuthProvider  string `json:"authProvider"`
-	ConfigProfile string `json:"configProfile"`
-	TenancyMode   string `json:"tenancymode"`
-	TenancyName   string `json:"tenancyName,omitempty"`
-	Environment   string `json:"environment"`
+	AuthProvider   string   `json:"authProvider"`
+	ConfigProfile  string   `json:"configProfile"`
+	ConfigFilePath string   `json:"configFilePath,omitempty"`
+	ConfigProfiles []string `json:"configProfiles,omitempty"`
+	TenancyMode    string   `json:"tenancymode"`
+	TenancyName    string   `json:"tenancyName,omitempty"`
+	Environment    string   `json:"environment"`
+
+	Profiles []ProfileSettings `json:"profiles,omitempty"`
+
+	CrossTenancies []CrossTenancySettings `json:"crossTenancies,omitempty"`
+
+	Endpoints EndpointSettings `json:"endpoints,omitempty"`
 
+	// HTTP(S) proxy of the OCI clients, the password is the httpProxyPassword secured setting
+	HTTPProxyURL      string `json:"httpProxyUrl,omitempty"`
+	HTTPProxyUsername string `json:"httpProxyUsername,omitempty"`
+	// Grafana secure socks proxy, configured by the secureSocksProxy* settings read by the plugin SDK
+	EnableSecureSocksProxy bool `json:"enableSecureSocksProxy,omitempty"`
+	// Minimum TLS version of the OCI clients, the CA bundle and client certificate are the tlsCACert,
+	// tlsClientCert and tlsClientKey secured settings
+	TLSMinVersion string `json:"tlsMinVersion,omitempty"`
+	// Timeout of the queries in seconds, 0 for no timeout, can be overridden per query
+	QueryTimeout int `json:"queryTimeout,omitempty"`
+	// Return the data collected before a failing result page or interval, with a warning, instead of failing the query
+	PartialResults bool `json:"partialResults,omitempty"`
+	// Size in minutes of the chunks searched in parallel by log records queries, 0 for the default, -1 to never split the time range
+	SearchChunkSize int `json:"searchChunkSize,omitempty"`
+
+	// Legacy numbered profile settings, read by UserProfiles when the profile list is not set
 	Profile_0 string `json:"profile0,omitempty"`
 	Region_0  string `json:"region0,omitempty"`
 
@@ -40,21 +92,25 @@ type OCIDatasourceSettings struct {
 	Profile_5 string `json:"profile5,omitempty"`
 	Region_5  string `json:"region5,omitempty"`
 
-	Xtenancy_0 string `json:"xtenancy0,omitempty"`
-
 	CustomRegion_0 string `json:"customregion0,omitempty"`
 	CustomRegion_1 string `json:"customregion1,omitempty"`
 	CustomRegion_2 string `json:"customregion2,omitempty"`
 	CustomRegion_3 string `json:"customregion3,omitempty"`
 	CustomRegion_4 string `json:"customregion4,omitempty"`
 	CustomRegion_5 string `json:"customregion5,omitempty"`
+
+	// Legacy single cross-tenancy target, read by TargetTenancies when the cross-tenancy list is not set
+	Xtenancy_0 string `json:"xtenancy0,omitempty"`
 }
 
 // Load initializes the OCIDatasourceSettings from the provided backend.DataSourceInstanceSettings.
 // It unmarshals the JSONData from the DataSourceInstanceSettings into the OCIDatasourceSettings struct.
 // If the JSONData is not nil and has more than one element, it attempts to unmarshal it.
 // If unmarshalling fails, it returns an error indicating the failure.
-// Additionally, it sets the ConfigProfile to the default instance profile.
+// The endpoint overrides, the proxy settings and the cross-tenancy targets of instance principals are validated.
+// Additionally, it sets the ConfigProfile to the default instance profile, except for session token
+// authentication where it names the profile of the OCI config file holding the session, and defaults
+// the OCI config file path for the environments reading it.
 //
 // Parameters:
 // - dsiSettings: backend.DataSourceInstanceSettings containing the settings to load.
@@ -70,8 +126,167 @@ func (d *OCIDatasourceSettings) Load(dsiSettings backend.DataSourceInstanceSetti
 		}
 	}
 
-	// in case of instance principle auth provider
-	d.ConfigProfile = constants.DEFAULT_INSTANCE_PROFILE
+	if d.Environment == constants.Environment_SessionToken || d.Environment == constants.Environment_ConfigFile {
+		if d.ConfigFilePath == "" {
+			d.ConfigFilePath = constants.DefaultOCIConfigFilePath
+		}
+	}
+	if d.Environment == constants.Environment_SessionToken {
+		if d.ConfigProfile == "" {
+			d.ConfigProfile = constants.DefaultOCIConfigProfile
+		}
+	} else {
+		// in case of instance principle auth provider
+		d.ConfigProfile = constants.DEFAULT_INSTANCE_PROFILE
+	}
+
+	if err = d.validateEndpoints(); err != nil {
+		return err
+	}
+	if err = d.validateProxy(); err != nil {
+		return err
+	}
+	if d.TLSMinVersion != "" && d.TLSMinVersion != "1.2" && d.TLSMinVersion != "1.3" {
+		return fmt.Errorf("minimum TLS version %q is not supported, use 1.2 or 1.3", d.TLSMinVersion)
+	}
+	if d.SearchChunkSize < -1 {
+		return fmt.Errorf("search chunk size %d is not valid, use a number of minutes, 0 for the default or -1 to disable chunking", d.SearchChunkSize)
+	}
+	if d.QueryTimeout < 0 || d.QueryTimeout > constants.MaxQueryTimeout {
+		return fmt.Errorf("query timeout %d is out of range, use 0 to %d seconds", d.QueryTimeout, constants.MaxQueryTimeout)
+	}
+
+	if d.Environment == constants.Environment_Instance {
+		return d.validateTargetTenancies()
+	}
+
+	return nil
+}
+
+// UserProfiles returns the user principal profiles of the datasource.
+// When the profile list is not set, the profiles are migrated from the legacy numbered fields
+// Profile_0 to Profile_5, stopping at the first one without a name as the legacy settings did.
+//
+// Returns:
+// - []ProfileSettings: The profiles, in the order matching the index suffix of their secured settings.
+func (d *OCIDatasourceSettings) UserProfiles() []ProfileSettings {
+	if len(d.Profiles) > 0 {
+		return d.Profiles
+	}
+
+	legacy := []ProfileSettings{
+		{Name: d.Profile_0, Region: d.Region_0, CustomRegion: d.CustomRegion_0},
+		{Name: d.Profile_1, Region: d.Region_1, CustomRegion: d.CustomRegion_1},
+		{Name: d.Profile_2, Region: d.Region_2, CustomRegion: d.CustomRegion_2},
+		{Name: d.Profile_3, Region: d.Region_3, CustomRegion: d.CustomRegion_3},
+		{Name: d.Profile_4, Region: d.Region_4, CustomRegion: d.CustomRegion_4},
+		{Name: d.Profile_5, Region: d.Region_5, CustomRegion: d.CustomRegion_5},
+	}
+	profiles := make([]ProfileSettings, 0, len(legacy))
+	for _, profile := range legacy {
+		if profile.Name == "" {
+			break
+		}
+		// A custom region took precedence over the region in the legacy settings
+		profile.Dedicated = profile.CustomRegion != ""
+		profiles = append(profiles, profile)
+	}
+
+	return profiles
+}
+
+// TargetTenancies returns the cross-tenancy targets of an instance principal.
+// When the cross-tenancy list is not set, the legacy Xtenancy_0 field is returned as a target named DEFAULT.
+//
+// Returns:
+// - []CrossTenancySettings: The target tenancies, empty if the instance principal reads its own tenancy only.
+func (d *OCIDatasourceSettings) TargetTenancies() []CrossTenancySettings {
+	if len(d.CrossTenancies) > 0 {
+		return d.CrossTenancies
+	}
+	if d.Xtenancy_0 != "" {
+		return []CrossTenancySettings{{Name: constants.DefaultOCIConfigProfile, TenancyOCID: d.Xtenancy_0}}
+	}
+	return nil
+}
 
+// validateTargetTenancies checks that each cross-tenancy target has a unique friendly name, usable in a
+// tenancy access key, and a tenancy OCID.
+//
+// Returns:
+// - error: An error naming the offending target, otherwise nil.
+func (d *OCIDatasourceSettings) validateTargetTenancies() error {
+	names := make(map[string]bool)
+	for index, target := range d.TargetTenancies() {
+		switch {
+		case target.Name == "":
+			return fmt.Errorf("cross tenancy #%d has no name", index+1)
+		case strings.Contains(target.Name, "/"):
+			return fmt.Errorf("cross tenancy %s: the name cannot contain '/'", target.Name)
+		case names[target.Name]:
+			return fmt.Errorf("cross tenancy %s is configured more than once", target.Name)
+		case !strings.HasPrefix(target.TenancyOCID, "ocid1.tenancy."):
+			return fmt.Errorf("cross tenancy %s: %q is not a tenancy OCID", target.Name, target.TenancyOCID)
+		}
+		names[target.Name] = true
+	}
+	return nil
+}
+
+// validateEndpoints checks that the endpoint overrides of the datasource and of its profiles are
+// valid URLs or host names.
+//
+// Returns:
+// - error: An error naming the offending endpoint, otherwise nil.
+func (d *OCIDatasourceSettings) validateEndpoints() error {
+	if err := d.Endpoints.validate(); err != nil {
+		return err
+	}
+	for _, profile := range d.Profiles {
+		if err := profile.Endpoints.validate(); err != nil {
+			return fmt.Errorf("profile %s: %w", profile.Name, err)
+		}
+	}
+	return nil
+}
+
+// validate checks that each endpoint override is either empty or a URL or host name without path.
+func (e EndpointSettings) validate() error {
+	for _, endpoint := range []struct{ name, value string }{
+		{"logging search", e.LoggingSearch},
+		{"logging management", e.LoggingManagement},
+		{"identity", e.Identity},
+	} {
+		value := strings.TrimSpace(endpoint.value)
+		if value == "" {
+			continue
+		}
+		if !strings.Contains(value, "://") {
+			value = "https://" + value
+		}
+		u, err := url.Parse(value)
+		if err != nil || u.Host == "" || strings.Trim(u.Path, "/") != "" {
+			return fmt.Errorf("%s endpoint %q is not a valid endpoint", endpoint.name, endpoint.value)
+		}
+	}
+	return nil
+}
+
+// validateProxy checks that the HTTP proxy URL is an http or https URL, and that the HTTP proxy and the
+// secure socks proxy are not both enabled.
+//
+// Returns:
+// - error: An error describing the invalid proxy settings, otherwise nil.
+func (d *OCIDatasourceSettings) validateProxy() error {
+	if d.HTTPProxyURL == "" {
+		return nil
+	}
+	if d.EnableSecureSocksProxy {
+		return fmt.Errorf("the HTTP proxy and the secure socks proxy cannot be used together")
+	}
+	u, err := url.Parse(d.HTTPProxyURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("HTTP proxy %q is not a valid http or https URL", d.HTTPProxyURL)
+	}
 	return nil
 }
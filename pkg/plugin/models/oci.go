@@ -13,6 +13,12 @@ type OCIResource struct {
 	OCID string `json:"ocid,omitempty"`
 }
 
+// VariableValue represents a value of a template variable, e.g. the display name of a log with its OCID as value.
+type VariableValue struct {
+	Text  string `json:"text"`
+	Value string `json:"value"`
+}
+
 // The label fields for the log metric representing key-value metadata for a label.
 type LabelFieldMetadata struct {
 	LabelName  string
@@ -36,3 +42,83 @@ type GrafanaSearchLogsRequest struct {
 	MaxDataPoints        int32  // The maximum number of data points to return in the response.
 	PanelId              string // The ID of the Grafana panel requesting the log data.
 }
+
+// LogContextResult holds the log records surrounding a given log record, in time order.
+type LogContextResult struct {
+	Before []map[string]interface{} `json:"before"` // The log records preceding the given record, oldest first
+	After  []map[string]interface{} `json:"after"`  // The log records following the given record, oldest first
+}
+
+// TenancyCredentials holds the result of reloading the credentials of a tenancy.
+type TenancyCredentials struct {
+	Tenancy  string `json:"tenancy"`         // The tenancy access key
+	Reloaded bool   `json:"reloaded"`        // Whether the credentials were read again, false for credentials managed by Grafana or OCI
+	Error    string `json:"error,omitempty"` // The reason the credentials could not be read again
+}
+
+// CredentialsValidation holds the result of the re-validation of the credentials of a datasource.
+type CredentialsValidation struct {
+	Status    string               `json:"status"`  // ok or error
+	Message   string               `json:"message"` // The connectivity test result
+	Tenancies []TenancyCredentials `json:"tenancies"`
+}
+
+// LiveTailChannel holds the channel of the live tail of a query.
+type LiveTailChannel struct {
+	Path string `json:"path"` // The path of the channel, within the namespace of the datasource
+}
+
+// Results of a health check of a tenancy.
+const (
+	HealthCheckOK      = "ok"
+	HealthCheckFailed  = "failed"
+	HealthCheckUnknown = "unknown"
+)
+
+// TenancyHealth holds the result of the health checks of a tenancy.
+type TenancyHealth struct {
+	Key             string   `json:"key"`                // The tenancy access key
+	Name            string   `json:"name"`               // The profile or cross tenancy name
+	TenancyOCID     string   `json:"tenancyOCID"`        // The OCID of the tenancy
+	Region          string   `json:"region"`             // The region of the clients of the tenancy
+	Healthy         bool     `json:"healthy"`            // Authenticated, region reachable and log search permitted
+	Auth            string   `json:"auth"`               // ok, failed or unknown when the region is unreachable
+	RegionReachable bool     `json:"regionReachable"`    // Whether the endpoints of the region answer
+	LoggingSearch   bool     `json:"loggingSearch"`      // Whether searching the logs of the tenancy is permitted
+	ListLogGroups   bool     `json:"listLogGroups"`      // Whether listing the log groups of the tenancy is permitted
+	Errors          []string `json:"errors,omitempty"`   // The errors of the failed checks
+	Warnings        []string `json:"warnings,omitempty"` // The errors of the checks that do not fail the tenancy
+}
+
+// HealthReport holds the health of every tenancy of a datasource.
+type HealthReport struct {
+	Message   string          `json:"message"`   // Names the healthy and the failing tenancies
+	Healthy   int             `json:"healthy"`   // The number of healthy tenancies
+	Failing   int             `json:"failing"`   // The number of failing tenancies
+	Tenancies []TenancyHealth `json:"tenancies"` // The health of each tenancy
+}
+
+// OperationDiagnostic holds the result of an OCI operation probed by the IAM diagnostics.
+type OperationDiagnostic struct {
+	Operation  string `json:"operation"`            // The OCI operation, e.g. logging:ListLogGroups
+	Permission string `json:"permission"`           // The permission the operation needs, e.g. read log-groups
+	OK         bool   `json:"ok"`                   // Whether the operation succeeded
+	Skipped    bool   `json:"skipped,omitempty"`    // Whether the operation could not be probed
+	StatusCode int    `json:"statusCode,omitempty"` // The HTTP status returned by OCI on failure
+	Code       string `json:"code,omitempty"`       // The OCI error code, e.g. NotAuthorizedOrNotFound
+	Message    string `json:"message,omitempty"`    // The error message, or the reason the operation was skipped
+	Policy     string `json:"policy,omitempty"`     // The policy statement granting the permission
+}
+
+// DiagnosticsResult holds the result of the IAM diagnostics of a tenancy.
+type DiagnosticsResult struct {
+	Tenancy     string                `json:"tenancy"`            // The tenancy access key
+	TenancyOCID string                `json:"tenancyOCID"`        // The OCID of the tenancy
+	Compartment string                `json:"compartment"`        // The OCID of the probed compartment
+	LogGroup    string                `json:"logGroup,omitempty"` // The OCID of the probed log group
+	Principal   string                `json:"principal"`          // The subject of the policy statements
+	Operations  []OperationDiagnostic `json:"operations"`         // The result of each probed operation
+	Failing     []string              `json:"failing,omitempty"`  // The failing operations
+	Policies    []string              `json:"policies,omitempty"` // The policy statements granting the failing operations
+	Notes       []string              `json:"notes,omitempty"`    // Hints to read the results
+}
@@ -11,4 +11,22 @@ type QueryModel struct {
 	TenancyName string `json:"tenancyName"`
 	TenancyOCID string `json:"tenancy"`
 	Region      string `json:"region"`
+	FillMode    string `json:"fillMode,omitempty"`  // How empty rounddown buckets are filled: "", "zero", "null" or "previous"
+	LogVolume   bool   `json:"logVolume,omitempty"` // Set by Explore to get the log volume histogram of a log records query
+	Timeout     int    `json:"timeout,omitempty"`   // Timeout of the query in seconds, overrides the timeout of the datasource
+
+	SortDirection string `json:"sortDirection,omitempty"` // Time order of the log records of queries without a sort command: "asc" or "desc"
+
+	Annotation AnnotationModel `json:"annotation,omitempty"` // Mapping of log fields to annotation fields
+}
+
+// AnnotationModel describes how the log records returned by an annotation query are mapped to
+// annotations. Field names are paths within the log content, e.g. "time", "data.message" or "oracle.logid".
+type AnnotationModel struct {
+	TimeField    string `json:"timeField,omitempty"`    // Field holding the annotation time, defaults to "time"
+	TimeEndField string `json:"timeEndField,omitempty"` // Optional field holding the annotation end time
+	TitleField   string `json:"titleField,omitempty"`   // Optional field holding the annotation title
+	TextField    string `json:"textField,omitempty"`    // Field holding the annotation text, defaults to "data.message"
+	TagsField    string `json:"tagsField,omitempty"`    // Optional comma separated list of fields used as tags
+	Limit        int    `json:"limit,omitempty"`        // Maximum number of annotations returned
 }
@@ -0,0 +1,197 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/oracle/oci-go-sdk/v65/loggingsearch"
+
+	"github.com/oracle/oci-grafana-logs/pkg/plugin/constants"
+)
+
+// Largest integer below which every integer is exactly represented as a float64, the OCI SDK decoding
+// the numbers of the log search results as float64
+const maxExactFloatInteger = 1 << 53
+
+// numericFieldValueType returns the type of the values of the metric field of a log metrics query. Counts
+// are integers and the results of the other aggregation functions, e.g. averages and sums, are floating
+// point numbers. When the aggregation function is not known, the field info returned by OCI is used.
+//
+// Parameters:
+//   - function: The aggregation function of the metric field, e.g. "count" or "avg", empty if not known.
+//   - fieldType: The type of the metric field in the field info of the log search results, empty if not returned.
+//
+// Returns:
+//   - constants.FieldValueType: The type of the values, ValueType_Undefined if the field is not numeric.
+func numericFieldValueType(function string, fieldType loggingsearch.FieldInfoFieldTypeEnum) constants.FieldValueType {
+	switch strings.ToLower(function) {
+	case "count":
+		return constants.ValueType_Int
+	case "sum", "avg", "min", "max":
+		return constants.ValueType_Float64
+	}
+	if fieldType == loggingsearch.FieldInfoFieldTypeNumber {
+		return constants.ValueType_Float64
+	}
+	return constants.ValueType_Undefined
+}
+
+// searchFieldTypes indexes the field info of log search results by field name.
+//
+// Parameters:
+//   - fields: The field info of the log search results.
+//
+// Returns:
+//   - map[string]loggingsearch.FieldInfoFieldTypeEnum: The type of each field.
+func searchFieldTypes(fields []loggingsearch.FieldInfo) map[string]loggingsearch.FieldInfoFieldTypeEnum {
+	fieldTypes := make(map[string]loggingsearch.FieldInfoFieldTypeEnum, len(fields))
+	for _, field := range fields {
+		if field.FieldName != nil {
+			fieldTypes[*field.FieldName] = field.FieldType
+		}
+	}
+	return fieldTypes
+}
+
+// numericConverter converts the values of the metric field of a log metrics query to the type of the field,
+// keeping track of the values that cannot be converted so that they are reported rather than dropped silently.
+type numericConverter struct {
+	field    string
+	failures int
+	example  string
+}
+
+// int64Value converts a value of the metric field to an integer.
+//
+// Parameters:
+//   - value: The value decoded from the log search results.
+//
+// Returns:
+//   - *int64: The integer, nil for a null value or a value that cannot be converted.
+func (c *numericConverter) int64Value(value interface{}) *int64 {
+	var result int64
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case float64:
+		if v != math.Trunc(v) || math.Abs(v) > maxExactFloatInteger {
+			c.fail(value)
+			return nil
+		}
+		result = int64(v)
+	case int64:
+		result = v
+	case int:
+		result = int64(v)
+	case json.Number:
+		parsed, err := v.Int64()
+		if err != nil {
+			c.fail(value)
+			return nil
+		}
+		result = parsed
+	case string:
+		parsed, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		if err != nil {
+			c.fail(value)
+			return nil
+		}
+		result = parsed
+	default:
+		c.fail(value)
+		return nil
+	}
+	return &result
+}
+
+// float64Value converts a value of the metric field to a floating point number.
+//
+// Parameters:
+//   - value: The value decoded from the log search results.
+//
+// Returns:
+//   - *float64: The number, nil for a null value or a value that cannot be converted.
+func (c *numericConverter) float64Value(value interface{}) *float64 {
+	var result float64
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case float64:
+		result = v
+	case int64:
+		result = float64(v)
+	case int:
+		result = float64(v)
+	case json.Number:
+		parsed, err := v.Float64()
+		if err != nil {
+			c.fail(value)
+			return nil
+		}
+		result = parsed
+	case string:
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			c.fail(value)
+			return nil
+		}
+		result = parsed
+	default:
+		c.fail(value)
+		return nil
+	}
+	return &result
+}
+
+// fail records a value that cannot be converted.
+func (c *numericConverter) fail(value interface{}) {
+	if c.failures == 0 {
+		c.example = fmt.Sprintf("%v", value)
+	}
+	c.failures++
+}
+
+// result returns the error of the query processing with the conversion failures, if any.
+//
+// Parameters:
+//   - err: The error of the query processing, nil on success.
+//
+// Returns:
+//   - error: A *valueConversionError wrapping err when values could not be converted, err otherwise.
+func (c *numericConverter) result(err error) error {
+	if c.failures == 0 {
+		return err
+	}
+	return &valueConversionError{
+		notice: data.Notice{
+			Severity: data.NoticeSeverityWarning,
+			Text: fmt.Sprintf("%d values of the %s field could not be converted to numbers and are missing, e.g. %q",
+				c.failures, c.field, c.example),
+		},
+		err: err,
+	}
+}
+
+// valueConversionError is returned by the log metrics processing functions, together with their data, when
+// values of the metric field could not be converted. The query returns the data with a warning notice.
+type valueConversionError struct {
+	notice data.Notice
+	err    error
+}
+
+func (e *valueConversionError) Error() string {
+	return e.notice.Text
+}
+
+func (e *valueConversionError) Unwrap() error {
+	return e.err
+}
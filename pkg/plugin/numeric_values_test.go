@@ -0,0 +1,135 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/oracle/oci-go-sdk/v65/loggingsearch"
+
+	"github.com/oracle/oci-grafana-logs/pkg/plugin/constants"
+)
+
+func TestNumericFieldValueType(t *testing.T) {
+	tests := []struct {
+		function  string
+		fieldType loggingsearch.FieldInfoFieldTypeEnum
+		want      constants.FieldValueType
+	}{
+		{function: "count", want: constants.ValueType_Int},
+		{function: "COUNT", fieldType: loggingsearch.FieldInfoFieldTypeNumber, want: constants.ValueType_Int},
+		{function: "avg", want: constants.ValueType_Float64},
+		{function: "sum", want: constants.ValueType_Float64},
+		{fieldType: loggingsearch.FieldInfoFieldTypeNumber, want: constants.ValueType_Float64},
+		{fieldType: loggingsearch.FieldInfoFieldTypeString, want: constants.ValueType_Undefined},
+		{want: constants.ValueType_Undefined},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.function+"/"+string(tt.fieldType), func(t *testing.T) {
+			if got := numericFieldValueType(tt.function, tt.fieldType); got != tt.want {
+				t.Fatalf("numericFieldValueType() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNumericConverterInt64Value(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		want     *int64
+		wantFail bool
+	}{
+		{name: "null", value: nil},
+		{name: "integral float", value: float64(42), want: int64Pointer(42)},
+		{name: "negative float", value: float64(-3), want: int64Pointer(-3)},
+		{name: "fractional float", value: 1.5, wantFail: true},
+		{name: "float beyond exact integers", value: float64(1 << 60), wantFail: true},
+		{name: "int64", value: int64(7), want: int64Pointer(7)},
+		{name: "int", value: 7, want: int64Pointer(7)},
+		{name: "json number", value: json.Number("9007199254740993"), want: int64Pointer(9007199254740993)},
+		{name: "fractional json number", value: json.Number("1.5"), wantFail: true},
+		{name: "string", value: " 12 ", want: int64Pointer(12)},
+		{name: "text", value: "twelve", wantFail: true},
+		{name: "bool", value: true, wantFail: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &numericConverter{field: "count"}
+			got := c.int64Value(tt.value)
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Fatalf("int64Value(%v) = %v, want %v", tt.value, got, tt.want)
+			}
+			if (c.failures > 0) != tt.wantFail {
+				t.Fatalf("int64Value(%v) failures = %d, wantFail %v", tt.value, c.failures, tt.wantFail)
+			}
+		})
+	}
+}
+
+func TestNumericConverterFloat64Value(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		want     *float64
+		wantFail bool
+	}{
+		{name: "null", value: nil},
+		{name: "float", value: 1.5, want: float64Pointer(1.5)},
+		{name: "int64", value: int64(2), want: float64Pointer(2)},
+		{name: "int", value: 2, want: float64Pointer(2)},
+		{name: "json number", value: json.Number("2.25"), want: float64Pointer(2.25)},
+		{name: "string", value: " 3.5 ", want: float64Pointer(3.5)},
+		{name: "text", value: "n/a", wantFail: true},
+		{name: "map", value: map[string]interface{}{}, wantFail: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &numericConverter{field: "avg"}
+			got := c.float64Value(tt.value)
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Fatalf("float64Value(%v) = %v, want %v", tt.value, got, tt.want)
+			}
+			if (c.failures > 0) != tt.wantFail {
+				t.Fatalf("float64Value(%v) failures = %d, wantFail %v", tt.value, c.failures, tt.wantFail)
+			}
+		})
+	}
+}
+
+func TestNumericConverterResult(t *testing.T) {
+	processErr := errors.New("search failed")
+
+	c := &numericConverter{field: "count"}
+	c.int64Value(float64(1))
+	if err := c.result(processErr); err != processErr {
+		t.Fatalf("result() without failures = %v, want the processing error", err)
+	}
+
+	c.int64Value("first")
+	c.int64Value("second")
+	err := c.result(processErr)
+	var conversion *valueConversionError
+	if !errors.As(err, &conversion) {
+		t.Fatalf("result() = %v, want a *valueConversionError", err)
+	}
+	if !strings.Contains(conversion.notice.Text, `2 values of the count field`) || !strings.Contains(conversion.notice.Text, `"first"`) {
+		t.Fatalf("result() notice = %q, want the failure count and the first value", conversion.notice.Text)
+	}
+	if !errors.Is(err, processErr) {
+		t.Fatal("result() does not wrap the processing error")
+	}
+}
+
+func int64Pointer(v int64) *int64 { return &v }
+
+func float64Pointer(v float64) *float64 { return &v }
@@ -0,0 +1,320 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"bufio"
+	"crypto/rsa"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/pkg/errors"
+
+	"github.com/oracle/oci-grafana-logs/pkg/plugin/constants"
+)
+
+// configFileProvider is a configuration provider for a user principal profile of an OCI config file.
+//
+// The profile and its key file are read into an immutable snapshot. Both files are checked for changes
+// at most every ConfigFileReloadInterval seconds and read again when modified, so that rotated keys are
+// used without editing the datasource. If the files cannot be read again, the last valid snapshot is kept.
+// The provider is also the request signer of its clients, so that the key ID and the private key of a
+// signature always come from the same snapshot.
+type configFileProvider struct {
+	configFilePath string
+	profile        string
+
+	mu            sync.Mutex
+	current       *configFileSnapshot
+	keyFilePath   string
+	configModTime time.Time
+	keyModTime    time.Time
+	lastCheck     time.Time
+}
+
+// configFileSnapshot holds the configuration of a profile read at once, with its key ID and decrypted
+// private key resolved from the same files.
+type configFileSnapshot struct {
+	provider   common.ConfigurationProvider
+	keyID      string
+	privateKey *rsa.PrivateKey
+}
+
+// KeyID returns the key ID of the snapshot.
+func (s *configFileSnapshot) KeyID() (string, error) {
+	return s.keyID, nil
+}
+
+// PrivateRSAKey returns the private API key of the snapshot.
+func (s *configFileSnapshot) PrivateRSAKey() (*rsa.PrivateKey, error) {
+	return s.privateKey, nil
+}
+
+// expandHomePath replaces a leading "~" in a path with the home folder of the user running the plugin,
+// as the OCI CLI does for the paths of its configuration file.
+//
+// Parameters:
+//   - path: The path to expand.
+//
+// Returns:
+//   - string: The expanded path.
+func expandHomePath(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
+
+// readOCIConfigFile reads an OCI CLI configuration file and returns the key/value pairs of each profile.
+// Keys are lower cased, lines starting with "#" or ";" are ignored, and the paths relative to the home
+// folder are expanded.
+//
+// Parameters:
+//   - path: The path of the OCI CLI configuration file.
+//
+// Returns:
+//   - map[string]map[string]string: The settings of each profile, indexed by profile name.
+//   - error: An error if the file cannot be read or is malformed.
+func readOCIConfigFile(path string) (map[string]map[string]string, error) {
+	file, err := os.Open(expandHomePath(path))
+	if err != nil {
+		return nil, errors.Wrap(err, "can not open OCI config file")
+	}
+	defer file.Close()
+
+	profiles := make(map[string]map[string]string)
+	var profile map[string]string
+
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			if _, ok := profiles[name]; !ok {
+				profiles[name] = make(map[string]string)
+			}
+			profile = profiles[name]
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found || profile == nil {
+			return nil, errors.Errorf("malformed OCI config file %s at line %d", path, lineNumber)
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		if strings.HasSuffix(key, "_file") {
+			value = expandHomePath(value)
+		}
+		profile[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "can not read OCI config file")
+	}
+
+	return profiles, nil
+}
+
+// listOCIConfigProfiles returns the sorted names of the profiles of an OCI config file.
+//
+// Parameters:
+//   - path: The path of the OCI CLI configuration file.
+//
+// Returns:
+//   - []string: The profile names.
+//   - error: An error if the file cannot be read.
+func listOCIConfigProfiles(path string) ([]string, error) {
+	profiles, err := readOCIConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// newConfigFileProvider creates a configuration provider for a profile of an OCI config file.
+//
+// Parameters:
+//   - configFilePath: The path of the OCI config file, "~" is expanded to the home folder.
+//   - profile: The name of the profile.
+//
+// Returns:
+//   - *configFileProvider: The configuration provider of the profile.
+//   - error: An error if the profile or its key file cannot be read or is incomplete.
+func newConfigFileProvider(configFilePath string, profile string) (*configFileProvider, error) {
+	p := &configFileProvider{
+		configFilePath: configFilePath,
+		profile:        profile,
+	}
+	if err := p.load(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// load reads the profile and its key file and replaces the current snapshot.
+// The caller must hold the lock of the provider, except from the constructor.
+func (p *configFileProvider) load() error {
+	configInfo, err := os.Stat(expandHomePath(p.configFilePath))
+	if err != nil {
+		return errors.Wrap(err, "can not read OCI config file")
+	}
+	profiles, err := readOCIConfigFile(p.configFilePath)
+	if err != nil {
+		return err
+	}
+	settings, ok := profiles[p.profile]
+	if !ok {
+		return errors.Errorf("profile %s not found in OCI config file %s", p.profile, p.configFilePath)
+	}
+	for _, key := range []string{"user", "fingerprint", "key_file", "tenancy", "region"} {
+		if settings[key] == "" {
+			return errors.Errorf("profile %s of OCI config file %s has no %s", p.profile, p.configFilePath, key)
+		}
+	}
+
+	keyInfo, err := os.Stat(settings["key_file"])
+	if err != nil {
+		return errors.Wrap(err, "can not read key file of profile "+p.profile)
+	}
+	privateKey, err := os.ReadFile(settings["key_file"])
+	if err != nil {
+		return errors.Wrap(err, "can not read key file of profile "+p.profile)
+	}
+	decryptedKey, err := loadPrivateKey(privateKey, settings["pass_phrase"], settings["fingerprint"])
+	if err != nil {
+		return errors.Errorf("key file %s of profile %s: %s", settings["key_file"], p.profile, err.Error())
+	}
+
+	provider := common.NewRawConfigurationProvider(settings["tenancy"], settings["user"], settings["region"], settings["fingerprint"], decryptedKey, nil)
+	keyID, err := provider.KeyID()
+	if err != nil {
+		return errors.Wrap(err, "invalid profile "+p.profile)
+	}
+	rsaKey, err := provider.PrivateRSAKey()
+	if err != nil {
+		return errors.Wrap(err, "invalid key file of profile "+p.profile)
+	}
+
+	p.current = &configFileSnapshot{provider: provider, keyID: keyID, privateKey: rsaKey}
+	p.keyFilePath = settings["key_file"]
+	p.configModTime = configInfo.ModTime()
+	p.keyModTime = keyInfo.ModTime()
+	p.lastCheck = time.Now()
+
+	return nil
+}
+
+// snapshot returns the current snapshot of the profile, after reading the files again if they changed.
+func (p *configFileProvider) snapshot() *configFileSnapshot {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if time.Since(p.lastCheck) < constants.ConfigFileReloadInterval*time.Second {
+		return p.current
+	}
+	p.lastCheck = time.Now()
+
+	configInfo, configErr := os.Stat(expandHomePath(p.configFilePath))
+	keyInfo, keyErr := os.Stat(p.keyFilePath)
+	if configErr == nil && keyErr == nil && configInfo.ModTime().Equal(p.configModTime) && keyInfo.ModTime().Equal(p.keyModTime) {
+		return p.current
+	}
+
+	if err := p.load(); err != nil {
+		backend.Logger.Error("plugin.oci_config_file", "snapshot", "could not reload OCI config file, keeping the previous configuration", "profile", p.profile, "error", err)
+	} else {
+		backend.Logger.Info("plugin.oci_config_file", "snapshot", "OCI config file reloaded", "profile", p.profile)
+	}
+	return p.current
+}
+
+// TenancyOCID returns the tenancy OCID of the profile.
+func (p *configFileProvider) TenancyOCID() (string, error) {
+	return p.snapshot().provider.TenancyOCID()
+}
+
+// UserOCID returns the user OCID of the profile.
+func (p *configFileProvider) UserOCID() (string, error) {
+	return p.snapshot().provider.UserOCID()
+}
+
+// KeyFingerprint returns the fingerprint of the API key of the profile.
+func (p *configFileProvider) KeyFingerprint() (string, error) {
+	return p.snapshot().provider.KeyFingerprint()
+}
+
+// Region returns the region of the profile.
+func (p *configFileProvider) Region() (string, error) {
+	return p.snapshot().provider.Region()
+}
+
+// KeyID returns the key ID of the API key of the profile.
+func (p *configFileProvider) KeyID() (string, error) {
+	return p.snapshot().keyID, nil
+}
+
+// PrivateRSAKey returns the private API key of the profile.
+func (p *configFileProvider) PrivateRSAKey() (*rsa.PrivateKey, error) {
+	return p.snapshot().privateKey, nil
+}
+
+// AuthType returns the authentication type of the profile.
+func (p *configFileProvider) AuthType() (common.AuthConfig, error) {
+	return p.snapshot().provider.AuthType()
+}
+
+// Sign signs a request with the key ID and the private key of a single snapshot of the profile, so that a
+// reload between reading the key ID and the private key cannot mix the keys of two snapshots.
+//
+// Parameters:
+//   - request: The request to sign.
+//
+// Returns:
+//   - error: An error if the request cannot be signed.
+func (p *configFileProvider) Sign(request *http.Request) error {
+	return common.DefaultRequestSigner(p.snapshot()).Sign(request)
+}
+
+// Refreshable tells the OCI SDK that the credentials of the provider may change over time.
+func (p *configFileProvider) Refreshable() bool {
+	return true
+}
+
+// ReloadCredentials reads the profile and its key file again, whether they changed or not.
+// On failure the previous configuration is kept.
+//
+// Returns:
+//   - error: An error if the profile or its key file cannot be read or is invalid.
+func (p *configFileProvider) ReloadCredentials() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.load(); err != nil {
+		return err
+	}
+	backend.Logger.Info("plugin.oci_config_file", "ReloadCredentials", "OCI config file reloaded", "profile", p.profile)
+	return nil
+}
@@ -0,0 +1,43 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfigFileProviderSign(t *testing.T) {
+	keyFile, err := filepath.Abs(filepath.Join("testdata", "private_keys", "pkcs8_sha256_aes256.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	configFile := filepath.Join(t.TempDir(), "config")
+	config := "[DEFAULT]\nuser=ocid1.user.oc1..user\nfingerprint=" + testKeyFingerprint + "\nkey_file=" + keyFile +
+		"\npass_phrase=" + testKeyPassphrase + "\ntenancy=ocid1.tenancy.oc1..tenancy\nregion=us-ashburn-1\n"
+	if err := os.WriteFile(configFile, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	provider, err := newConfigFileProvider(configFile, "DEFAULT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	request, _ := http.NewRequest(http.MethodGet, "https://logging.us-ashburn-1.oci.oraclecloud.com/20200531/logGroups", nil)
+	request.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	if err := provider.Sign(request); err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	wantKeyID := `keyId="ocid1.tenancy.oc1..tenancy/ocid1.user.oc1..user/` + testKeyFingerprint + `"`
+	if authorization := request.Header.Get("Authorization"); !strings.Contains(authorization, wantKeyID) {
+		t.Fatalf("Sign() Authorization = %s, want %s", authorization, wantKeyID)
+	}
+}
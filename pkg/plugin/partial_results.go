@@ -0,0 +1,139 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/pkg/errors"
+
+	"github.com/oracle/oci-grafana-logs/pkg/plugin/constants"
+)
+
+// partialResultsError is returned by the query processing functions, together with the data collected so
+// far, when the search stopped before the last result page or the last interval of the query. The query
+// returns the collected data with a warning notice instead of failing.
+type partialResultsError struct {
+	reason string
+	err    error
+}
+
+func (e *partialResultsError) Error() string {
+	return e.reason
+}
+
+func (e *partialResultsError) Unwrap() error {
+	return e.err
+}
+
+// notice returns the warning attached to the frame of the partial results.
+func (e *partialResultsError) notice() data.Notice {
+	return data.Notice{Severity: data.NoticeSeverityWarning, Text: "Partial results: " + e.reason}
+}
+
+// interruptedSearch returns the error of a search interrupted by the context of the query. When the deadline
+// of the query is hit after some data was collected, the data is kept with a partial results error. When the
+// query is cancelled, e.g. the user navigated away, the search error is returned since no one waits for it.
+//
+// Parameters:
+//   - ctx: The context of the query.
+//   - collected: The number of pages or intervals collected before the search stopped.
+//   - position: Where the search stopped, e.g. "while fetching page 3".
+//
+// Returns:
+//   - error: A *partialResultsError or the error of the context.
+func interruptedSearch(ctx context.Context, collected int, position string) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) && collected > 0 {
+		return &partialResultsError{reason: "the query deadline was hit " + position, err: ctx.Err()}
+	}
+	return ctx.Err()
+}
+
+// failedSearch returns the error of a search operation that failed after some result pages or intervals were
+// collected. When the datasource returns partial results, the error is a partial results error naming the
+// failing page or interval and the OCI error code; otherwise it is nil and the query fails with the error.
+//
+// Parameters:
+//   - err: The error of the search operation.
+//   - collected: The number of pages or intervals collected before the failure.
+//   - position: The failing page or interval, e.g. "page 7".
+//   - returned: What is returned instead, e.g. ", 6000 log records returned".
+//
+// Returns:
+//   - error: A *partialResultsError, or nil when the query must fail.
+func (o *OCIDatasource) failedSearch(err error, collected int, position string, returned string) error {
+	if !o.settings.PartialResults || collected == 0 {
+		return nil
+	}
+	cause := ": " + err.Error()
+	if serviceErr, ok := common.IsServiceError(err); ok {
+		cause = fmt.Sprintf(" with %s (HTTP %d): %s", serviceErr.GetCode(), serviceErr.GetHTTPStatusCode(), serviceErr.GetMessage())
+	}
+	return &partialResultsError{reason: position + " failed" + cause + returned, err: err}
+}
+
+// queryTimeout returns the timeout of a query: the timeout of the query when set, otherwise the timeout
+// of the datasource. No timeout is applied when none is set.
+//
+// Parameters:
+//   - queryTimeout: The timeout of the query in seconds, 0 when not set.
+//   - datasourceTimeout: The timeout of the datasource in seconds, 0 when not set.
+//
+// Returns:
+//   - time.Duration: The timeout, 0 for no timeout.
+func queryTimeout(queryTimeout int, datasourceTimeout int) time.Duration {
+	timeout := queryTimeout
+	if timeout <= 0 {
+		timeout = datasourceTimeout
+	}
+	if timeout <= 0 {
+		return 0
+	}
+	return time.Duration(min(timeout, constants.MaxQueryTimeout)) * time.Second
+}
+
+// trimFieldValues reduces the values of the log records fields to the number of records collected.
+//
+// Parameters:
+//   - mFieldDefns: The field definitions, whose values are allocated for the maximum number of records.
+//   - count: The number of records collected.
+func trimFieldValues(mFieldDefns map[string]*DataFieldElements, count int) {
+	for _, dataFieldDefn := range mFieldDefns {
+		if dataFieldDefn.Type == FieldValueType(constants.ValueType_Time) {
+			timeValuesSlice, _ := dataFieldDefn.Values.([]*time.Time)
+			dataFieldDefn.Values = timeValuesSlice[:count]
+		} else if dataFieldDefn.Type == FieldValueType(constants.ValueType_Float64) {
+			floatValuesSlice, _ := dataFieldDefn.Values.([]*float64)
+			dataFieldDefn.Values = floatValuesSlice[:count]
+		} else if dataFieldDefn.Type == FieldValueType(constants.ValueType_Int) {
+			intValuesSlice, _ := dataFieldDefn.Values.([]*int64)
+			dataFieldDefn.Values = intValuesSlice[:count]
+		} else { // Treat all other data types as a string (including string fields)
+			stringValuesSlice, _ := dataFieldDefn.Values.([]*string)
+			dataFieldDefn.Values = stringValuesSlice[:count]
+		}
+	}
+}
+
+// timeoutError describes a query that hit its deadline before any data was collected. Without a
+// configured timeout, the deadline is the one of the Grafana request and the error is kept unchanged.
+//
+// Parameters:
+//   - timeout: The timeout of the query, 0 when no timeout is configured.
+//   - err: The error of the search.
+//
+// Returns:
+//   - error: The error returned for the query.
+func timeoutError(timeout time.Duration, err error) error {
+	if timeout <= 0 {
+		return err
+	}
+	return errors.Wrap(err, fmt.Sprintf("the query timed out after %s, narrow the time range or increase the timeout", timeout))
+}
@@ -0,0 +1,23 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeoutError(t *testing.T) {
+	if err := timeoutError(0, context.DeadlineExceeded); err != context.DeadlineExceeded {
+		t.Fatalf("timeoutError(0) = %v, want the error unchanged", err)
+	}
+	err := timeoutError(30*time.Second, context.DeadlineExceeded)
+	if err == nil || !strings.Contains(err.Error(), "timed out after 30s") {
+		t.Fatalf("timeoutError(30s) = %v, want the configured timeout", err)
+	}
+}
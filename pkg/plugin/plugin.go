@@ -5,12 +5,11 @@ package plugin
 import (
 	"context"
 	"encoding/json"
-	"encoding/pem"
 	"fmt"
 	"net/http"
-	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
@@ -44,12 +43,27 @@ type logTenancyAccess struct {
 	loggingManagementClient logging.LoggingManagementClient
 	identityClient          identity.IdentityClient
 	config                  common.ConfigurationProvider
+	endpoints               endpointSettings
 }
 
 type OCIDatasource struct {
 	tenancyAccess map[string]*logTenancyAccess
 	logger        log.Logger
 	nameToOCID    map[string]string
+	uid           string
+	// inflight is read locked by each request, and locked by Dispose to wait for the in-flight requests
+	inflight sync.RWMutex
+	// streams is cancelled by Dispose to stop the live tail streams of the instance
+	streams     context.Context
+	stopStreams context.CancelFunc
+	// generation of the registration of the clients of the instance in the client registry
+	generation uint64
+	// clients of the previous instance of the datasource, and clients of this instance while it is built
+	previousTenancies map[string]registeredTenancy
+	builtTenancies    map[string]registeredTenancy
+	// HTTP client of the OCI clients when a proxy is configured, and the hash of its settings
+	httpClient     *http.Client
+	httpClientHash string
 	// timeCacheUpdated time.Time
 	backend.CallResourceHandler
 	// clients  *client.OCIClients
@@ -66,67 +80,10 @@ type OCIConfigFile struct {
 	privkeypass  map[string]*string
 	customregion map[string]string
 	customdomain map[string]string
+	endpoints    map[string]models.EndpointSettings
 	logger       log.Logger
 }
 
-type OCISecuredSettings struct {
-	Profile_0      string `json:"profile0,omitempty"`
-	Tenancy_0      string `json:"tenancy0,omitempty"`
-	Region_0       string `json:"region0,omitempty"`
-	User_0         string `json:"user0,omitempty"`
-	Privkey_0      string `json:"privkey0,omitempty"`
-	Fingerprint_0  string `json:"fingerprint0,omitempty"`
-	CustomRegion_0 string `json:"customregion0,omitempty"`
-	CustomDomain_0 string `json:"customdomain0,omitempty"`
-
-	Profile_1      string `json:"profile1,omitempty"`
-	Tenancy_1      string `json:"tenancy1,omitempty"`
-	Region_1       string `json:"region1,omitempty"`
-	User_1         string `json:"user1,omitempty"`
-	Fingerprint_1  string `json:"fingerprint1,omitempty"`
-	Privkey_1      string `json:"privkey1,omitempty"`
-	CustomRegion_1 string `json:"customregion1,omitempty"`
-	CustomDomain_1 string `json:"customdomain1,omitempty"`
-
-	Profile_2      string `json:"profile2,omitempty"`
-	Tenancy_2      string `json:"tenancy2,omitempty"`
-	Region_2       string `json:"region2,omitempty"`
-	User_2         string `json:"user2,omitempty"`
-	Fingerprint_2  string `json:"fingerprint2,omitempty"`
-	Privkey_2      string `json:"privkey2,omitempty"`
-	CustomRegion_2 string `json:"customregion2,omitempty"`
-	CustomDomain_2 string `json:"customdomain2,omitempty"`
-
-	Profile_3      string `json:"profile3,omitempty"`
-	Tenancy_3      string `json:"tenancy3,omitempty"`
-	Region_3       string `json:"region3,omitempty"`
-	User_3         string `json:"user3,omitempty"`
-	Fingerprint_3  string `json:"fingerprint3,omitempty"`
-	Privkey_3      string `json:"privkey3,omitempty"`
-	CustomRegion_3 string `json:"customregion3,omitempty"`
-	CustomDomain_3 string `json:"customdomain3,omitempty"`
-
-	Profile_4      string `json:"profile4,omitempty"`
-	Tenancy_4      string `json:"tenancy4,omitempty"`
-	Region_4       string `json:"region4,omitempty"`
-	User_4         string `json:"user4,omitempty"`
-	Fingerprint_4  string `json:"fingerprint4,omitempty"`
-	Privkey_4      string `json:"privkey4,omitempty"`
-	CustomRegion_4 string `json:"customregion4,omitempty"`
-	CustomDomain_4 string `json:"customdomain4,omitempty"`
-
-	Profile_5      string `json:"profile5,omitempty"`
-	Tenancy_5      string `json:"tenancy5,omitempty"`
-	Region_5       string `json:"region5,omitempty"`
-	User_5         string `json:"user5,omitempty"`
-	Fingerprint_5  string `json:"fingerprint5,omitempty"`
-	Privkey_5      string `json:"privkey5,omitempty"`
-	CustomRegion_5 string `json:"customregion5,omitempty"`
-	CustomDomain_5 string `json:"customdomain5,omitempty"`
-
-	Xtenancy_0 string `json:"xtenancy0,omitempty"`
-}
-
 // NewOCIConfigFile - constructor
 func NewOCIConfigFile() *OCIConfigFile {
 	return &OCIConfigFile{
@@ -138,30 +95,38 @@ func NewOCIConfigFile() *OCIConfigFile {
 		privkeypass:  make(map[string]*string),
 		customregion: make(map[string]string),
 		customdomain: make(map[string]string),
+		endpoints:    make(map[string]models.EndpointSettings),
 		logger:       log.DefaultLogger,
 	}
 }
 
 // NewOCIDatasourceConstructor - constructor
 func NewOCIDatasourceConstructor() *OCIDatasource {
+	streams, stopStreams := context.WithCancel(context.Background())
 	return &OCIDatasource{
 		tenancyAccess: make(map[string]*logTenancyAccess),
 		//monTenancyAccess: make(map[string]*TenancyAccess),
-		logger:     log.DefaultLogger,
-		nameToOCID: make(map[string]string),
+		logger:      log.DefaultLogger,
+		nameToOCID:  make(map[string]string),
+		streams:     streams,
+		stopStreams: stopStreams,
 	}
 }
 
 // NewOCIDatasource creates a new instance of OCIDatasource with the provided settings.
 // It initializes the datasource settings, config provider, and cache, and registers HTTP routes.
+// When the settings of a datasource change, Grafana creates a new instance: the clients of the tenancies
+// whose settings did not change are reused from the previous instance, which is disposed once its
+// in-flight requests complete.
 //
 // Parameters:
+//   - ctx: context.Context of the instance creation, holding the Grafana configuration.
 //   - settings: backend.DataSourceInstanceSettings containing the datasource instance settings.
 //
 // Returns:
 //   - instancemgmt.Instance: The created OCIDatasource instance.
 //   - error: An error if the datasource creation fails.
-func NewOCIDatasource(settings backend.DataSourceInstanceSettings) (instancemgmt.Instance, error) {
+func NewOCIDatasource(ctx context.Context, settings backend.DataSourceInstanceSettings) (instancemgmt.Instance, error) {
 	o := NewOCIDatasourceConstructor()
 	dsSettings := &models.OCIDatasourceSettings{}
 
@@ -170,12 +135,23 @@ func NewOCIDatasource(settings backend.DataSourceInstanceSettings) (instancemgmt
 		return nil, err
 	}
 	o.settings = dsSettings
+	o.uid = settings.UID
+	httpClient, httpClientHash, err := newHTTPDispatcher(ctx, settings, dsSettings)
+	if err != nil {
+		backend.Logger.Error("plugin", "NewOCIDatasource", "failed to configure the proxy and TLS settings: "+err.Error())
+		return nil, err
+	}
+	o.httpClient, o.httpClientHash = httpClient, httpClientHash
 	if len(o.tenancyAccess) == 0 {
-
+		// Clients of tenancies whose settings did not change since the previous instance are reused
+		o.previousTenancies = clientRegistry.snapshot(o.uid)
+		o.builtTenancies = make(map[string]registeredTenancy)
 		err := o.getConfigProvider(dsSettings.Environment, dsSettings.TenancyMode, settings)
 		if err != nil {
 			return nil, err
 		}
+		o.generation = clientRegistry.replace(o.uid, o.builtTenancies)
+		o.previousTenancies, o.builtTenancies = nil, nil
 	}
 
 	cache, err := ristretto.NewCache(&ristretto.Config{
@@ -217,7 +193,8 @@ func NewOCIDatasource(settings backend.DataSourceInstanceSettings) (instancemgmt
 // - The Values array is preallocated based on the field type and `totalSamples` count:
 //   - Time fields: []*time.Time
 //   - Float fields: []*float64
-//   - Integer fields: []*int
+//   - Integer fields: []*int64
+//   - JSON fields: []*json.RawMessage
 //   - String fields: []*string
 //
 // - The new DataFieldElements object is added to the `dataFieldDefns` map and returned.
@@ -250,7 +227,9 @@ func (o *OCIDatasource) getCreateDataFieldElemsForField(dataFieldDefns map[strin
 		} else if fieldType == FieldValueType(constants.ValueType_Float64) {
 			dataFieldDefn.Values = make([]*float64, totalSamples)
 		} else if fieldType == FieldValueType(constants.ValueType_Int) {
-			dataFieldDefn.Values = make([]*int, totalSamples)
+			dataFieldDefn.Values = make([]*int64, totalSamples)
+		} else if fieldType == FieldValueType(constants.ValueType_JSON) {
+			dataFieldDefn.Values = make([]*json.RawMessage, totalSamples)
 		} else { // Treat all other data types as a string (including string fields)
 			dataFieldDefn.Values = make([]*string, totalSamples)
 		}
@@ -277,6 +256,9 @@ func (o *OCIDatasource) getCreateDataFieldElemsForField(dataFieldDefns map[strin
 // - Each query's data frame is created using the data fields and added to the response object.
 // - The response is returned with all the processed data frames for each query.
 func (o *OCIDatasource) QueryData(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+	o.inflight.RLock()
+	defer o.inflight.RUnlock()
+
 	// create response struct
 	response := backend.NewQueryDataResponse()
 
@@ -286,19 +268,31 @@ func (o *OCIDatasource) QueryData(ctx context.Context, req *backend.QueryDataReq
 		//var mFieldData = make(map[string]*DataFieldElements)
 		// Create an array of data.Field pointers, one for each data field definition in the
 		// field definition map
-		mFieldData, res := o.query(ctx, req.PluginContext, q)
+		mFieldData, notices, res := o.query(ctx, req.PluginContext, q)
 
 		dfFields := make([]*data.Field, len(mFieldData))
 		// saving the response in a hashmap based on with RefID as identifier
 		response.Responses[q.RefID] = res
 		respD := response.Responses[q.RefID]
 		fieldCnt := 0
-		for _, fieldDataElems := range mFieldData {
+		for _, fieldKey := range orderedFieldKeys(mFieldData) {
+			fieldDataElems := mFieldData[fieldKey]
 			dfFields[fieldCnt] = data.NewField(fieldDataElems.Name, fieldDataElems.Labels, fieldDataElems.Values)
 			fieldCnt += 1
 		}
 		// Create a new data Frame using the generated Fields while referencing the query ID
 		frame = data.NewFrame(q.RefID, dfFields...)
+		// Declare numeric results as wide time series so they can be used by alerting and
+		// recording rules through Grafana server side expressions
+		if isNumericFrame(mFieldData) {
+			frame.SetMeta(&data.FrameMeta{Type: data.FrameTypeTimeSeriesWide})
+		}
+		if len(notices) > 0 {
+			if frame.Meta == nil {
+				frame.SetMeta(&data.FrameMeta{})
+			}
+			frame.Meta.Notices = append(frame.Meta.Notices, notices...)
+		}
 
 		// Add the current frame to the list of frames for all of the provided queries
 		respD.Frames = append(respD.Frames, frame)
@@ -312,11 +306,16 @@ func (o *OCIDatasource) QueryData(ctx context.Context, req *backend.QueryDataReq
 // The main use case for these health checks is the test button on the
 // datasource configuration page which allows users to verify that
 // a datasource is working as expected.
+// Every tenancy is tested at the same time; the message names the healthy and the failing
+// tenancies, and the JSON details hold the result of each check of each tenancy.
 func (o *OCIDatasource) CheckHealth(ctx context.Context, req *backend.CheckHealthRequest) (*backend.CheckHealthResult, error) {
 	backend.Logger.Debug("plugin", "CheckHealth", req.PluginContext.PluginID)
+	o.inflight.RLock()
+	defer o.inflight.RUnlock()
 
 	hRes := &backend.CheckHealthResult{}
-	if err := o.TestConnectivity(ctx); err != nil {
+	report, err := o.CheckTenanciesHealth(ctx)
+	if err != nil {
 		hRes.Status = backend.HealthStatusError
 		hRes.Message = err.Error()
 		backend.Logger.Error("plugin", "error in CheckHealth", err)
@@ -324,121 +323,110 @@ func (o *OCIDatasource) CheckHealth(ctx context.Context, req *backend.CheckHealt
 		return hRes, nil
 	}
 
-	return &backend.CheckHealthResult{
-		Status:  backend.HealthStatusOk,
-		Message: "Success",
-	}, nil
+	if report.Failing > 0 {
+		backend.Logger.Error("plugin", "error in CheckHealth", report.Message)
+	}
+	return healthCheckResult(report), nil
 }
 
-// OCILoadSettings loads and processes OCI configuration settings from the Grafana data source instance settings.
+// healthCheckResult converts the health report of the tenancies into the result of a health check: the
+// check fails when a tenancy is failing, and the health of each tenancy is shown in the details of the
+// test result.
 //
-// This function handles both secured and non-secured settings, merging them to create a comprehensive
-// configuration. It iterates through the settings, parsing and storing them in an OCIConfigFile struct.
-// The function supports multiple tenancy configurations, identified by a numerical suffix (e.g., _0, _1).
+// Parameters:
+//   - report: The health report of the tenancies.
+//
+// Returns:
+//   - *backend.CheckHealthResult: The result of the health check.
+func healthCheckResult(report models.HealthReport) *backend.CheckHealthResult {
+	hRes := &backend.CheckHealthResult{Status: backend.HealthStatusOk, Message: report.Message}
+	if report.Failing > 0 {
+		hRes.Status = backend.HealthStatusError
+	}
+	details, err := json.Marshal(map[string]interface{}{"tenancies": report.Tenancies})
+	if err != nil {
+		backend.Logger.Error("plugin", "CheckHealth", "could not marshal health details: "+err.Error())
+	}
+	hRes.JSONDetails = details
+
+	return hRes
+}
+
+// OCILoadSettings loads and validates the user principal profiles from the Grafana data source instance settings.
+//
+// The non secured settings of the profiles (name, region and dedicated region) are read from the profile list of
+// the JSON data, or from the legacy numbered fields when the list is not set. The secured settings of the profile
+// at index i are read from the decrypted secure JSON data, using the field name with the i suffix, e.g. tenancy0.
+// Encrypted private keys are decrypted with the privkeypass<i> passphrase, and each key is checked against the
+// configured fingerprint. In single tenancy mode only the DEFAULT profile is loaded.
 //
 // Parameters:
 //   - req: backend.DataSourceInstanceSettings - The data source instance settings from Grafana.
 //
 // Returns:
-//   - *OCIConfigFile: A pointer to an OCIConfigFile struct containing the parsed settings.
-//   - error: An error if any issues occur during the loading or parsing of settings.
-//
-// The function performs the following steps:
-//  1. Initializes an empty OCIConfigFile.
-//  2. Unmarshals the JSON data from req.JSONData into both OCISecuredSettings and OCIDatasourceSettings structs.
-//  3. Merges the non-secured settings into the secured settings.
-//  4. Iterates through the fields of the OCISecuredSettings struct using reflection.
-//  5. Parses the field names to determine the tenancy block index (e.g., _0, _1).
-//  6. Extracts the profile name as the key for each tenancy block.
-//  7. Stores the tenancy OCID, region, user, private key, fingerprint, private key passphrase, custom region, and custom domain in the OCIConfigFile.
-//  8. Handles multiple tenancy blocks by incrementing the TenancySettingsBlock index.
-//  9. Returns the populated OCIConfigFile or an error if any step fails.
+//   - *OCIConfigFile: A pointer to an OCIConfigFile struct containing the settings of each profile, indexed by profile name.
+//   - error: An error naming the offending profile if a profile is incomplete or invalid.
 func OCILoadSettings(req backend.DataSourceInstanceSettings) (*OCIConfigFile, error) {
 	q := NewOCIConfigFile()
 
-	// Load secured and non-secured settings
-	TenancySettingsBlock := 0
-	var dat OCISecuredSettings
-	var nonsecdat models.OCIDatasourceSettings
-
-	if err := json.Unmarshal(req.JSONData, &dat); err != nil {
-		return nil, fmt.Errorf("can not read Secured settings: %s", err.Error())
-	}
-
-	if err := json.Unmarshal(req.JSONData, &nonsecdat); err != nil {
+	var settings models.OCIDatasourceSettings
+	if err := json.Unmarshal(req.JSONData, &settings); err != nil {
 		return nil, fmt.Errorf("can not read settings: %s", err.Error())
 	}
+	secured := req.DecryptedSecureJSONData
 
-	// merge non secured settings into secured
-	decryptedJSONData := req.DecryptedSecureJSONData
-	transcode(decryptedJSONData, &dat)
-
-	dat.Region_0 = nonsecdat.Region_0
-	dat.Region_1 = nonsecdat.Region_1
-	dat.Region_2 = nonsecdat.Region_2
-	dat.Region_3 = nonsecdat.Region_3
-	dat.Region_4 = nonsecdat.Region_4
-	dat.Region_5 = nonsecdat.Region_5
-
-	dat.Profile_0 = nonsecdat.Profile_0
-	dat.Profile_1 = nonsecdat.Profile_1
-	dat.Profile_2 = nonsecdat.Profile_2
-	dat.Profile_3 = nonsecdat.Profile_3
-	dat.Profile_4 = nonsecdat.Profile_4
-	dat.Profile_5 = nonsecdat.Profile_5
-
-	dat.Xtenancy_0 = nonsecdat.Xtenancy_0
-
-	dat.CustomRegion_0 = nonsecdat.CustomRegion_0
-	dat.CustomRegion_1 = nonsecdat.CustomRegion_1
-	dat.CustomRegion_2 = nonsecdat.CustomRegion_2
-	dat.CustomRegion_3 = nonsecdat.CustomRegion_3
-	dat.CustomRegion_4 = nonsecdat.CustomRegion_4
-	dat.CustomRegion_5 = nonsecdat.CustomRegion_5
-
-	v := reflect.ValueOf(dat)
-	typeOfS := v.Type()
-	var key string
-
-	for FieldIndex := 0; FieldIndex < v.NumField(); FieldIndex++ {
-		splits := strings.Split(typeOfS.Field(FieldIndex).Name, "_")
-		SettingsBlockIndex, interr := strconv.Atoi(splits[1])
-		if interr != nil {
-			return nil, fmt.Errorf("can not read settings: %s", interr.Error())
+	for index, profile := range settings.UserProfiles() {
+		key := profile.Name
+		if key == "" {
+			return nil, fmt.Errorf("profile #%d has no name", index+1)
+		}
+		if settings.TenancyMode != "multitenancy" && key != constants.DefaultOCIConfigProfile {
+			continue
+		}
+		if _, ok := q.tenancyocid[key]; ok {
+			return nil, fmt.Errorf("profile %s is configured more than once", key)
 		}
 
-		if SettingsBlockIndex == TenancySettingsBlock {
-			if splits[0] == "Profile" {
-				if v.Field(FieldIndex).Interface() != "" {
-					key = fmt.Sprintf("%v", v.Field(FieldIndex).Interface())
-				} else {
-					return q, nil
-				}
-			} else {
-				switch value := v.Field(FieldIndex).Interface(); strings.ToLower(splits[0]) {
-				case "tenancy":
-					q.tenancyocid[key] = fmt.Sprintf("%v", value)
-				case "region":
-					q.region[key] = fmt.Sprintf("%v", value)
-				case "user":
-					q.user[key] = fmt.Sprintf("%v", value)
-				case "privkey":
-					q.privkey[key] = fmt.Sprintf("%v", value)
-				case "fingerprint":
-					q.fingerprint[key] = fmt.Sprintf("%v", value)
-				case "privkeypass":
-					q.privkeypass[key] = EmptyKeyPass
-				case "customregion":
-					q.customregion[key] = fmt.Sprintf("%v", value)
-				case "customdomain":
-					q.customdomain[key] = fmt.Sprintf("%v", value)
-				}
+		suffix := strconv.Itoa(index)
+		for _, field := range []struct{ name, label string }{
+			{"tenancy", "tenancy OCID"},
+			{"user", "user OCID"},
+			{"fingerprint", "fingerprint"},
+			{"privkey", "private key"},
+		} {
+			if strings.TrimSpace(secured[field.name+suffix]) == "" {
+				return nil, fmt.Errorf("profile %s: %s is missing", key, field.label)
+			}
+		}
+		if profile.Dedicated {
+			if profile.CustomRegion == "" {
+				return nil, fmt.Errorf("profile %s: dedicated region is missing", key)
 			}
-		} else {
-			TenancySettingsBlock++
-			FieldIndex--
+		} else if profile.Region == "" {
+			return nil, fmt.Errorf("profile %s: region is missing", key)
+		}
+		// Decrypt the private key and check it against the fingerprint now, rather than on the first OCI request
+		privateKey, err := loadPrivateKey([]byte(secured["privkey"+suffix]), secured["privkeypass"+suffix], secured["fingerprint"+suffix])
+		if err != nil {
+			return nil, fmt.Errorf("profile %s: %s", key, err.Error())
+		}
+
+		q.tenancyocid[key] = strings.TrimSpace(secured["tenancy"+suffix])
+		q.user[key] = strings.TrimSpace(secured["user"+suffix])
+		q.fingerprint[key] = strings.TrimSpace(secured["fingerprint"+suffix])
+		q.privkey[key] = privateKey
+		q.privkeypass[key] = EmptyKeyPass
+		q.region[key] = profile.Region
+		q.endpoints[key] = profile.Endpoints
+		if profile.Dedicated {
+			q.customregion[key] = profile.CustomRegion
+			q.customdomain[key] = strings.TrimSpace(secured["customdomain"+suffix])
 		}
 	}
+	if len(q.tenancyocid) == 0 {
+		return nil, errors.New("no user principal profile is configured")
+	}
+
 	return q, nil
 }
 
@@ -457,114 +445,276 @@ func OCILoadSettings(req backend.DataSourceInstanceSettings) (*OCIConfigFile, er
 // - In "local" environment mode, it loads configuration settings from a file and sets up clients based on the user principal and the given configuration.
 //   - If the tenancy mode is "multitenancy", it creates separate configuration for each tenancy.
 //   - If the tenancy mode is "single tenancy", it uses the default configuration.
-//   - It validates the private key and handles any custom regions and domains specified in the configuration.
+//   - The profiles are validated by OCILoadSettings, and any custom regions and domains specified in the configuration are handled.
 //
 // - In "OCI Instance" environment mode, it configures using Instance Principal, including handling cross-tenancy configuration if provided.
+//   - If the tenancy mode is "multitenancy", each cross-tenancy target is exposed as a tenancy named after its friendly name.
+//   - If the tenancy mode is "single tenancy", the first cross-tenancy target, if any, replaces the tenancy of the instance.
+//
+// - In "OCI Resource Principal" environment mode, it configures using the Resource Principal of OCI Functions or Container Instances.
+// - In "OKE Workload Identity" environment mode, it configures using the workload identity of the OKE pod.
+// - In "OCI Session Token" environment mode, it configures using the OCI CLI session of a profile of the OCI config file.
+// - In "OCI Config File" environment mode, it configures using the user principal profiles of an OCI config file read from disk.
+//   - If the tenancy mode is "multitenancy", each selected profile (all profiles if none is selected) is exposed as a tenancy.
+//   - If the tenancy mode is "single tenancy", only the first selected profile (DEFAULT if none is selected) is used.
+//
+// - In every environment, the clients of a tenancy are reused from the previous instance of the datasource when the settings they are built from did not change, see tenancyAccessFor.
+//
+// - In every environment, the endpoint overrides of the settings replace the endpoints of the clients, see resolveServiceEndpoints.
+//
 // - The function returns an error if any of the required steps, such as loading configuration or creating clients, fails.
 func (o *OCIDatasource) getConfigProvider(environment string, tenancymode string, req backend.DataSourceInstanceSettings) error {
+	// Endpoint overrides of the datasource, the other endpoints are resolved by the OCI SDK from the region of the provider
+	endpoints := endpointSettings{Overrides: []models.EndpointSettings{o.settings.Endpoints}}
 
 	switch environment {
-	case "local":
+	case constants.Environment_Local:
 		log.DefaultLogger.Debug("Configuring using User Principals")
 		q, err := OCILoadSettings(req)
 		if err != nil {
-			return errors.New("Error Loading config settings")
+			return errors.Wrap(err, "error loading config settings")
 		}
 		for key := range q.tenancyocid {
-			var configProvider common.ConfigurationProvider
 			if tenancymode != "multitenancy" {
 				if key != "DEFAULT" {
 					backend.Logger.Error("Single Tenancy mode detected, skipping additional profile", "profile", key)
 					continue
 				}
 			}
-			// test if PEM key is valid
-			block, _ := pem.Decode([]byte(q.privkey[key]))
-			if block == nil {
-				return errors.New("Invalid Private Key")
-			}
-			// Override region in Configuration Provider in case a Custom region is configured
+			// A dedicated region replaces the region in the Configuration Provider, and its custom domain the realm domain of the endpoints
+			region := q.region[key]
 			if q.customregion[key] != "" {
-				backend.Logger.Error("getConfigProvider", "CustomRegion", q.customregion[key])
-				configProvider = common.NewRawConfigurationProvider(q.tenancyocid[key], q.user[key], q.customregion[key], q.fingerprint[key], q.privkey[key], q.privkeypass[key])
-			} else {
-				configProvider = common.NewRawConfigurationProvider(q.tenancyocid[key], q.user[key], q.region[key], q.fingerprint[key], q.privkey[key], q.privkeypass[key])
+				backend.Logger.Debug("getConfigProvider", "CustomRegion", q.customregion[key])
+				region = q.customregion[key]
 			}
+			profileEndpoints := endpointSettings{CustomDomain: q.customdomain[key], Overrides: []models.EndpointSettings{q.endpoints[key], o.settings.Endpoints}}
+			hash := settingsHash(environment, q.tenancyocid[key], q.user[key], region, q.fingerprint[key], q.privkey[key], profileEndpoints)
 
-			loggingSearchClient, err := loggingsearch.NewLogSearchClientWithConfigurationProvider(configProvider)
+			tenancyAccess, err := o.tenancyAccessFor(key, hash, func() (*logTenancyAccess, error) {
+				configProvider := common.NewRawConfigurationProvider(q.tenancyocid[key], q.user[key], region, q.fingerprint[key], q.privkey[key], q.privkeypass[key])
+				return newLogTenancyAccess(configProvider, profileEndpoints, o.httpClient)
+			})
 			if err != nil {
 				o.logger.Error("Error with config:" + key)
-				return errors.New("error with loggingSearchClient")
-			}
-			loggingManagementClient, err := logging.NewLoggingManagementClientWithConfigurationProvider(configProvider)
-			if err != nil {
-				o.logger.Error("Error with config:" + key)
-				return errors.New("Error creating loggingManagement client")
-			}
-			identityClient, err := identity.NewIdentityClientWithConfigurationProvider(configProvider)
-			if err != nil {
-				return errors.Wrap(err, "Error creating identity client")
-			}
-
-			// Override Identity and Telemetry EndPoint region and domain in case a Custom region is configured
-			if q.customdomain[key] != "" {
-				host_custom_telemetry := common.StringToRegion(q.customregion[key]).EndpointForTemplate("telemetry", "https://telemetry."+q.customregion[key]+"."+q.customdomain[key])
-				host_custom_identity := common.StringToRegion(q.customregion[key]).EndpointForTemplate("identity", "https://identity."+q.customregion[key]+"."+q.customdomain[key])
-				loggingSearchClient.Host = host_custom_telemetry
-				loggingManagementClient.Host = host_custom_telemetry
-				identityClient.Host = host_custom_identity
-				backend.Logger.Debug("getConfigProvider", "loggingSearchClient.Host", loggingSearchClient.Host)
-				backend.Logger.Debug("getConfigProvider", "loggingManagementClient.Host", loggingManagementClient.Host)
-				backend.Logger.Debug("getConfigProvider", "identityClient.Host", identityClient.Host)
-			}
-
-			tenancyocid, err := configProvider.TenancyOCID()
-			if err != nil {
-				return errors.New("error with TenancyOCID")
+				return err
 			}
 
 			if tenancymode == "multitenancy" {
 				//o.tenancyAccess[key+"/"+tenancyocid] = &TenancyAccess{monitoringClient, identityClient, configProvider}
-				o.tenancyAccess[key+"/"+tenancyocid] = &logTenancyAccess{loggingSearchClient, loggingManagementClient, identityClient, configProvider}
+				o.tenancyAccess[key+"/"+q.tenancyocid[key]] = tenancyAccess
 			} else {
 				//o.monTenancyAccess[SingleTenancyKey] = &TenancyAccess{monitoringClient, identityClient, configProvider}
-				o.tenancyAccess[SingleTenancyKey] = &logTenancyAccess{loggingSearchClient, loggingManagementClient, identityClient, configProvider}
+				o.tenancyAccess[SingleTenancyKey] = tenancyAccess
 			}
 		}
 		return nil
 
-	case "OCI Instance":
+	case constants.Environment_Instance:
 		log.DefaultLogger.Debug("Configuring using Instance Principal")
+		// The instance principal provider is created once, and only if the clients of a tenancy are built
 		var configProvider common.ConfigurationProvider
-		configProvider, err := auth.InstancePrincipalConfigurationProvider()
+		instancePrincipal := func() (common.ConfigurationProvider, error) {
+			if configProvider == nil {
+				provider, err := auth.InstancePrincipalConfigurationProviderWithCustomClient(federationClientModifier(o.httpClient))
+				if err != nil {
+					return nil, errors.New("error with instance principals")
+				}
+				configProvider = provider
+			}
+			return configProvider, nil
+		}
+		buildInstanceAccess := func() (*logTenancyAccess, error) {
+			provider, err := instancePrincipal()
+			if err != nil {
+				return nil, err
+			}
+			return newLogTenancyAccess(provider, endpoints, o.httpClient)
+		}
 
-		if err != nil {
-			return errors.New("error with instance principals")
+		targets := o.settings.TargetTenancies()
+		if tenancymode == "multitenancy" {
+			// Each target tenancy is exposed as its own tenancy, read through cross-tenancy policies
+			if len(targets) == 0 {
+				provider, err := instancePrincipal()
+				if err != nil {
+					return err
+				}
+				tocid, err := provider.TenancyOCID()
+				if err != nil {
+					return errors.New("error with TenancyOCID")
+				}
+				targets = []models.CrossTenancySettings{{Name: constants.DefaultOCIConfigProfile, TenancyOCID: tocid}}
+			}
+			for _, target := range targets {
+				log.DefaultLogger.Debug("Configuring using Cross Tenancy Instance Principal", "name", target.Name, "target", target.TenancyOCID)
+				tenancyAccess, err := o.tenancyAccessFor(target.Name, settingsHash(environment, target, endpoints), buildInstanceAccess)
+				if err != nil {
+					backend.Logger.Error("Error with config:" + target.Name)
+					return err
+				}
+				o.tenancyAccess[target.Name+"/"+target.TenancyOCID] = tenancyAccess
+			}
+			return nil
 		}
-		if o.settings.Xtenancy_0 != "" {
+
+		if len(targets) > 0 {
 			log.DefaultLogger.Debug("Configuring using Cross Tenancy Instance Principal")
-			tocid, _ := configProvider.TenancyOCID()
-			log.DefaultLogger.Debug("Source Tenancy OCID: " + tocid)
-			log.DefaultLogger.Debug("Target Tenancy OCID: " + o.settings.Xtenancy_0)
+			log.DefaultLogger.Debug("Target Tenancy OCID: " + targets[0].TenancyOCID)
 		}
-		loggingSearchClient, err := loggingsearch.NewLogSearchClientWithConfigurationProvider(configProvider)
+		tenancyAccess, err := o.tenancyAccessFor(SingleTenancyKey, settingsHash(environment, endpoints), buildInstanceAccess)
 		if err != nil {
 			backend.Logger.Error("Error with config:" + SingleTenancyKey)
-			return errors.New("error with client")
+			return err
 		}
-		loggingManagementClient, err := logging.NewLoggingManagementClientWithConfigurationProvider(configProvider)
+		o.tenancyAccess[SingleTenancyKey] = tenancyAccess
+		return nil
+
+	case constants.Environment_ResourcePrincipal:
+		log.DefaultLogger.Debug("Configuring using Resource Principal")
+		tenancyAccess, err := o.tenancyAccessFor(SingleTenancyKey, settingsHash(environment, endpoints), func() (*logTenancyAccess, error) {
+			configProvider, err := auth.ResourcePrincipalConfigurationProvider()
+			if err != nil {
+				return nil, errors.Wrap(err, "error with resource principal")
+			}
+			return newLogTenancyAccess(configProvider, endpoints, o.httpClient)
+		})
 		if err != nil {
-			o.logger.Error("Error with config:")
-			return errors.New("Error creating loggingManagement client")
+			backend.Logger.Error("Error with config:" + SingleTenancyKey)
+			return err
 		}
-		identityClient, err := identity.NewIdentityClientWithConfigurationProvider(configProvider)
+		o.tenancyAccess[SingleTenancyKey] = tenancyAccess
+		return nil
+
+	case constants.Environment_OkeWorkloadIdentity:
+		log.DefaultLogger.Debug("Configuring using OKE Workload Identity")
+		tenancyAccess, err := o.tenancyAccessFor(SingleTenancyKey, settingsHash(environment, endpoints), func() (*logTenancyAccess, error) {
+			configProvider, err := auth.OkeWorkloadIdentityConfigurationProvider()
+			if err != nil {
+				return nil, errors.Wrap(err, "error with OKE workload identity")
+			}
+			return newLogTenancyAccess(configProvider, endpoints, o.httpClient)
+		})
 		if err != nil {
-			return errors.New("Error creating identity client")
+			backend.Logger.Error("Error with config:" + SingleTenancyKey)
+			return err
+		}
+		o.tenancyAccess[SingleTenancyKey] = tenancyAccess
+		return nil
+
+	case constants.Environment_SessionToken:
+		log.DefaultLogger.Debug("Configuring using OCI Session Token", "configFile", o.settings.ConfigFilePath, "profile", o.settings.ConfigProfile)
+		hash := settingsHash(environment, o.settings.ConfigFilePath, o.settings.ConfigProfile, endpoints)
+		tenancyAccess, err := o.tenancyAccessFor(SingleTenancyKey, hash, func() (*logTenancyAccess, error) {
+			configProvider, err := newSessionTokenProvider(o.settings.ConfigFilePath, o.settings.ConfigProfile)
+			if err != nil {
+				return nil, errors.Wrap(err, "error with session token")
+			}
+			if o.httpClient != nil {
+				configProvider.httpClient = o.httpClient
+			}
+			return newLogTenancyAccess(configProvider, endpoints, o.httpClient)
+		})
+		if err != nil {
+			backend.Logger.Error("Error with config:" + SingleTenancyKey)
+			return err
+		}
+		o.tenancyAccess[SingleTenancyKey] = tenancyAccess
+		return nil
+
+	case constants.Environment_ConfigFile:
+		log.DefaultLogger.Debug("Configuring using OCI Config File", "configFile", o.settings.ConfigFilePath, "profiles", o.settings.ConfigProfiles)
+		profiles := o.settings.ConfigProfiles
+		if len(profiles) == 0 {
+			if tenancymode == "multitenancy" {
+				var err error
+				if profiles, err = listOCIConfigProfiles(o.settings.ConfigFilePath); err != nil {
+					return errors.Wrap(err, "error with OCI config file")
+				}
+			} else {
+				profiles = []string{constants.DefaultOCIConfigProfile}
+			}
+		}
+		if tenancymode != "multitenancy" && len(profiles) > 1 {
+			backend.Logger.Error("Single Tenancy mode detected, skipping additional profiles", "profiles", profiles[1:])
+			profiles = profiles[:1]
+		}
+
+		for _, profile := range profiles {
+			// The provider reloads the profile when the file changes, so the clients are reused as long as the path is the same
+			hash := settingsHash(environment, o.settings.ConfigFilePath, profile, endpoints)
+			tenancyAccess, err := o.tenancyAccessFor(profile, hash, func() (*logTenancyAccess, error) {
+				configProvider, err := newConfigFileProvider(o.settings.ConfigFilePath, profile)
+				if err != nil {
+					return nil, errors.Wrap(err, "error with OCI config file")
+				}
+				return newLogTenancyAccess(configProvider, endpoints, o.httpClient)
+			})
+			if err != nil {
+				o.logger.Error("Error with config:" + profile)
+				return err
+			}
+			if tenancymode == "multitenancy" {
+				tenancyocid, err := tenancyAccess.config.TenancyOCID()
+				if err != nil {
+					return errors.New("error with TenancyOCID")
+				}
+				o.tenancyAccess[profile+"/"+tenancyocid] = tenancyAccess
+			} else {
+				o.tenancyAccess[SingleTenancyKey] = tenancyAccess
+			}
 		}
-		o.tenancyAccess[SingleTenancyKey] = &logTenancyAccess{loggingSearchClient, loggingManagementClient, identityClient, configProvider}
 		return nil
 
 	default:
 		return errors.New("unknown environment type")
 	}
 }
+
+// newLogTenancyAccess creates the logging search, logging management and identity clients
+// for the given configuration provider.
+//
+// Parameters:
+//   - configProvider: The OCI configuration provider used to authenticate the clients.
+//   - endpoints: The endpoint settings of the clients, the endpoints without override are resolved from the region of the provider.
+//   - httpClient: The HTTP client of the clients when a proxy or TLS settings are configured, nil to keep the OCI SDK dispatcher.
+//
+// Returns:
+//   - *logTenancyAccess: The clients and configuration provider of the tenancy.
+//   - error: An error if any of the clients cannot be created.
+func newLogTenancyAccess(configProvider common.ConfigurationProvider, endpoints endpointSettings, httpClient *http.Client) (*logTenancyAccess, error) {
+	loggingSearchClient, err := loggingsearch.NewLogSearchClientWithConfigurationProvider(configProvider)
+	if err != nil {
+		return nil, errors.Wrap(err, "error with loggingSearchClient")
+	}
+	loggingManagementClient, err := logging.NewLoggingManagementClientWithConfigurationProvider(configProvider)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error creating loggingManagement client")
+	}
+	identityClient, err := identity.NewIdentityClientWithConfigurationProvider(configProvider)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error creating identity client")
+	}
+
+	tenancyAccess := &logTenancyAccess{loggingSearchClient, loggingManagementClient, identityClient, configProvider, endpoints}
+	// The custom domain of a dedicated region applies to the region of the provider
+	region := ""
+	if endpoints.CustomDomain != "" {
+		if region, err = configProvider.Region(); err != nil {
+			return nil, errors.Wrap(err, "error with region")
+		}
+	}
+	tenancyAccess.applyEndpoints(endpoints.resolve(region))
+	// Providers reloading their key sign the requests themselves, with a consistent key ID and private key
+	if signer, ok := configProvider.(common.HTTPRequestSigner); ok {
+		tenancyAccess.loggingSearchClient.Signer = signer
+		tenancyAccess.loggingManagementClient.Signer = signer
+		tenancyAccess.identityClient.Signer = signer
+	}
+	if httpClient != nil {
+		tenancyAccess.loggingSearchClient.HTTPClient = httpClient
+		tenancyAccess.loggingManagementClient.HTTPClient = httpClient
+		tenancyAccess.identityClient.HTTPClient = httpClient
+	}
+
+	return tenancyAccess, nil
+}
@@ -0,0 +1,258 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/md5"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"hash"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Object identifiers of the PKCS#5 v2.0 algorithms used by encrypted PKCS#8 private keys
+var (
+	oidPBES2          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHMACWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}
+	oidAES128CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	oidDESEDE3CBC     = asn1.ObjectIdentifier{1, 2, 840, 113549, 3, 7}
+)
+
+// errWrongPassphrase is returned when an encrypted private key cannot be decrypted with the configured passphrase.
+var errWrongPassphrase = errors.New("the passphrase does not decrypt the private key")
+
+// encryptedPrivateKeyInfo is the ASN.1 structure of an "ENCRYPTED PRIVATE KEY" PEM block (RFC 5208).
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+// pbes2Params holds the parameters of the PBES2 encryption scheme (RFC 8018).
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+// pbkdf2Params holds the parameters of the PBKDF2 key derivation function (RFC 8018).
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// parsePrivateKey decodes the PEM private key of a profile, decrypting it with the passphrase when it is encrypted.
+// Unencrypted and encrypted PKCS#1 ("RSA PRIVATE KEY") and PKCS#8 ("PRIVATE KEY", "ENCRYPTED PRIVATE KEY") keys
+// are supported.
+//
+// Parameters:
+//   - pemData: The PEM encoded private key.
+//   - passphrase: The passphrase of the private key, empty if the key is not encrypted.
+//
+// Returns:
+//   - *rsa.PrivateKey: The decrypted RSA private key.
+//   - error: An error describing why the key cannot be used.
+func parsePrivateKey(pemData []byte, passphrase string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, errors.New("the private key is not a valid PEM key")
+	}
+
+	var der []byte
+	switch {
+	case block.Type == "ENCRYPTED PRIVATE KEY":
+		if passphrase == "" {
+			return nil, errors.New("the private key is encrypted but no passphrase is configured")
+		}
+		decrypted, err := decryptPKCS8(block.Bytes, []byte(passphrase))
+		if err != nil {
+			return nil, err
+		}
+		der = decrypted
+	// Legacy PEM encryption of PKCS#1 keys, still produced by `openssl rsa -traditional`
+	case x509.IsEncryptedPEMBlock(block):
+		if passphrase == "" {
+			return nil, errors.New("the private key is encrypted but no passphrase is configured")
+		}
+		decrypted, err := x509.DecryptPEMBlock(block, []byte(passphrase))
+		if err != nil {
+			if err == x509.IncorrectPasswordError {
+				return nil, errWrongPassphrase
+			}
+			return nil, errors.Wrap(err, "can not decrypt the private key")
+		}
+		der = decrypted
+	case block.Type == "RSA PRIVATE KEY" || block.Type == "PRIVATE KEY":
+		der = block.Bytes
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q, expected a private key", block.Type)
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		if block.Type == "ENCRYPTED PRIVATE KEY" {
+			return nil, errWrongPassphrase
+		}
+		return nil, errors.Wrap(err, "can not parse the private key")
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("the private key is a %T, OCI API keys must be RSA keys", parsed)
+	}
+	return key, nil
+}
+
+// privateKeyFingerprint computes the OCI fingerprint of an API key: the MD5 hash of the DER encoded
+// public key, as colon separated hexadecimal bytes.
+//
+// Parameters:
+//   - key: The private API key.
+//
+// Returns:
+//   - string: The fingerprint of the key.
+//   - error: An error if the public key cannot be encoded.
+func privateKeyFingerprint(key *rsa.PrivateKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return "", errors.Wrap(err, "can not encode the public key")
+	}
+	sum := md5.Sum(der)
+	pairs := make([]string, len(sum))
+	for i, b := range sum {
+		pairs[i] = hex.EncodeToString([]byte{b})
+	}
+	return strings.Join(pairs, ":"), nil
+}
+
+// loadPrivateKey decrypts the private key of a profile, checks that it matches the configured fingerprint
+// and returns it as an unencrypted PKCS#1 PEM key, ready for the OCI SDK raw configuration provider.
+//
+// Parameters:
+//   - pemData: The PEM encoded private key.
+//   - passphrase: The passphrase of the private key, empty if the key is not encrypted.
+//   - fingerprint: The fingerprint configured for the key.
+//
+// Returns:
+//   - string: The unencrypted PEM private key.
+//   - error: An error describing why the key cannot be used.
+func loadPrivateKey(pemData []byte, passphrase string, fingerprint string) (string, error) {
+	key, err := parsePrivateKey(pemData, passphrase)
+	if err != nil {
+		return "", err
+	}
+	keyFingerprint, err := privateKeyFingerprint(key)
+	if err != nil {
+		return "", err
+	}
+	if !strings.EqualFold(keyFingerprint, strings.TrimSpace(fingerprint)) {
+		return "", fmt.Errorf("the private key does not match the fingerprint %s, its fingerprint is %s", strings.TrimSpace(fingerprint), keyFingerprint)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})), nil
+}
+
+// decryptPKCS8 decrypts the content of an "ENCRYPTED PRIVATE KEY" PEM block using PBES2 with PBKDF2.
+//
+// Parameters:
+//   - der: The DER encoded EncryptedPrivateKeyInfo.
+//   - passphrase: The passphrase of the private key.
+//
+// Returns:
+//   - []byte: The DER encoded PKCS#8 private key.
+//   - error: An error if the encryption scheme is not supported or the passphrase is wrong.
+func decryptPKCS8(der []byte, passphrase []byte) ([]byte, error) {
+	var info encryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, errors.Wrap(err, "can not parse the encrypted private key")
+	}
+	if !info.Algorithm.Algorithm.Equal(oidPBES2) {
+		return nil, fmt.Errorf("unsupported private key encryption %s, only PBES2 is supported", info.Algorithm.Algorithm)
+	}
+
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, errors.Wrap(err, "can not parse the private key encryption parameters")
+	}
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, fmt.Errorf("unsupported private key derivation function %s, only PBKDF2 is supported", params.KeyDerivationFunc.Algorithm)
+	}
+	var kdfParams pbkdf2Params
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdfParams); err != nil {
+		return nil, errors.Wrap(err, "can not parse the private key derivation parameters")
+	}
+
+	var prf func() hash.Hash
+	switch {
+	case len(kdfParams.PRF.Algorithm) == 0 || kdfParams.PRF.Algorithm.Equal(oidHMACWithSHA1):
+		prf = sha1.New
+	case kdfParams.PRF.Algorithm.Equal(oidHMACWithSHA256):
+		prf = sha256.New
+	case kdfParams.PRF.Algorithm.Equal(oidHMACWithSHA512):
+		prf = sha512.New
+	default:
+		return nil, fmt.Errorf("unsupported private key derivation hash %s", kdfParams.PRF.Algorithm)
+	}
+
+	var keyLength int
+	var newCipher func([]byte) (cipher.Block, error)
+	switch scheme := params.EncryptionScheme.Algorithm; {
+	case scheme.Equal(oidAES128CBC):
+		keyLength, newCipher = 16, aes.NewCipher
+	case scheme.Equal(oidAES192CBC):
+		keyLength, newCipher = 24, aes.NewCipher
+	case scheme.Equal(oidAES256CBC):
+		keyLength, newCipher = 32, aes.NewCipher
+	case scheme.Equal(oidDESEDE3CBC):
+		keyLength, newCipher = 24, des.NewTripleDESCipher
+	default:
+		return nil, fmt.Errorf("unsupported private key cipher %s", scheme)
+	}
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, errors.Wrap(err, "can not parse the private key cipher parameters")
+	}
+
+	blockCipher, err := newCipher(pbkdf2.Key(passphrase, kdfParams.Salt, kdfParams.IterationCount, keyLength, prf))
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != blockCipher.BlockSize() || len(info.EncryptedData) == 0 || len(info.EncryptedData)%blockCipher.BlockSize() != 0 {
+		return nil, errors.New("the encrypted private key is malformed")
+	}
+	decrypted := make([]byte, len(info.EncryptedData))
+	cipher.NewCBCDecrypter(blockCipher, iv).CryptBlocks(decrypted, info.EncryptedData)
+
+	// A wrong passphrase is usually detected by an invalid PKCS#7 padding
+	padding := int(decrypted[len(decrypted)-1])
+	if padding == 0 || padding > blockCipher.BlockSize() ||
+		!bytes.Equal(decrypted[len(decrypted)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, errWrongPassphrase
+	}
+	return decrypted[:len(decrypted)-padding], nil
+}
@@ -0,0 +1,112 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"bytes"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// The keys of testdata/private_keys are a single RSA key generated by OpenSSL, encrypted with the passphrase
+// "secret":
+//
+//	openssl genrsa -traditional -out pkcs1.pem 2048
+//	openssl rsa -in pkcs1.pem -traditional -aes128 -out pkcs1_aes128.pem
+//	openssl pkcs8 -topk8 -in pkcs1.pem -nocrypt -out pkcs8.pem
+//	openssl pkcs8 -topk8 -in pkcs1.pem -v2 aes-128-cbc -v2prf hmacWithSHA1 -out pkcs8_sha1_aes128.pem
+//	openssl pkcs8 -topk8 -in pkcs1.pem -v2 aes-256-cbc -v2prf hmacWithSHA1 -out pkcs8_sha1_aes256.pem
+//	openssl pkcs8 -topk8 -in pkcs1.pem -v2 aes-128-cbc -v2prf hmacWithSHA256 -out pkcs8_sha256_aes128.pem
+//	openssl pkcs8 -topk8 -in pkcs1.pem -v2 aes-256-cbc -v2prf hmacWithSHA256 -out pkcs8_sha256_aes256.pem
+const (
+	testKeyPassphrase  = "secret"
+	testKeyFingerprint = "de:e5:82:0a:f6:bc:25:49:a3:ce:46:3f:8f:c6:38:fe"
+)
+
+func readTestKey(t *testing.T, name string) []byte {
+	t.Helper()
+	pemData, err := os.ReadFile(filepath.Join("testdata", "private_keys", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pemData
+}
+
+func TestLoadPrivateKey(t *testing.T) {
+	want, err := parsePrivateKey(readTestKey(t, "pkcs1.pem"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		file          string
+		passphrase    string
+		fingerprint   string
+		wantErr       bool
+		wantErrString string
+	}{
+		{name: "PKCS#1", file: "pkcs1.pem", fingerprint: testKeyFingerprint},
+		{name: "PKCS#8", file: "pkcs8.pem", fingerprint: testKeyFingerprint},
+		{name: "PKCS#1 legacy encryption", file: "pkcs1_aes128.pem", passphrase: testKeyPassphrase, fingerprint: testKeyFingerprint},
+		{name: "PKCS#8 SHA1 AES-128", file: "pkcs8_sha1_aes128.pem", passphrase: testKeyPassphrase, fingerprint: testKeyFingerprint},
+		{name: "PKCS#8 SHA1 AES-256", file: "pkcs8_sha1_aes256.pem", passphrase: testKeyPassphrase, fingerprint: testKeyFingerprint},
+		{name: "PKCS#8 SHA256 AES-128", file: "pkcs8_sha256_aes128.pem", passphrase: testKeyPassphrase, fingerprint: testKeyFingerprint},
+		{name: "PKCS#8 SHA256 AES-256", file: "pkcs8_sha256_aes256.pem", passphrase: testKeyPassphrase, fingerprint: " DE:E5:82:0A:F6:BC:25:49:A3:CE:46:3F:8F:C6:38:FE "},
+		{name: "PKCS#1 legacy encryption wrong passphrase", file: "pkcs1_aes128.pem", passphrase: "wrong", fingerprint: testKeyFingerprint, wantErrString: errWrongPassphrase.Error()},
+		{name: "PKCS#8 wrong passphrase", file: "pkcs8_sha256_aes256.pem", passphrase: "wrong", fingerprint: testKeyFingerprint, wantErrString: errWrongPassphrase.Error()},
+		{name: "encrypted without passphrase", file: "pkcs8_sha1_aes128.pem", fingerprint: testKeyFingerprint, wantErr: true},
+		{name: "fingerprint mismatch", file: "pkcs8_sha256_aes128.pem", passphrase: testKeyPassphrase, fingerprint: "00:11:22:33:44:55:66:77:88:99:aa:bb:cc:dd:ee:ff", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadPrivateKey(readTestKey(t, tt.file), tt.passphrase, tt.fingerprint)
+			if tt.wantErrString != "" {
+				if err == nil || err.Error() != tt.wantErrString {
+					t.Fatalf("loadPrivateKey() error = %v, want %q", err, tt.wantErrString)
+				}
+				return
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadPrivateKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			key, err := parsePrivateKey([]byte(got), "")
+			if err != nil {
+				t.Fatalf("loadPrivateKey() returned an unreadable key: %v", err)
+			}
+			if !key.Equal(want) {
+				t.Fatal("loadPrivateKey() returned another key")
+			}
+		})
+	}
+}
+
+func TestDecryptPKCS8(t *testing.T) {
+	unencrypted, _ := pem.Decode(readTestKey(t, "pkcs8.pem"))
+	for _, file := range []string{"pkcs8_sha1_aes128.pem", "pkcs8_sha1_aes256.pem", "pkcs8_sha256_aes128.pem", "pkcs8_sha256_aes256.pem"} {
+		t.Run(file, func(t *testing.T) {
+			block, _ := pem.Decode(readTestKey(t, file))
+			got, err := decryptPKCS8(block.Bytes, []byte(testKeyPassphrase))
+			if err != nil {
+				t.Fatalf("decryptPKCS8() error = %v", err)
+			}
+			if !bytes.Equal(got, unencrypted.Bytes) {
+				t.Fatal("decryptPKCS8() does not return the unencrypted PKCS#8 key")
+			}
+		})
+	}
+
+	if _, err := decryptPKCS8([]byte("not DER"), []byte(testKeyPassphrase)); err == nil {
+		t.Fatal("decryptPKCS8() of a malformed key should fail")
+	}
+}
@@ -6,10 +6,14 @@ package plugin
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/pkg/errors"
 
+	"github.com/oracle/oci-grafana-logs/pkg/plugin/constants"
 	"github.com/oracle/oci-grafana-logs/pkg/plugin/models"
 )
 
@@ -23,14 +27,17 @@ import (
 //
 // Returns:
 // - map[string]*DataFieldElements: A map containing the processed data field elements, which will be included in the query response.
+// - []data.Notice: The notices attached to the frame of the query, e.g. when the results are partial.
 // - backend.DataResponse: A response struct containing any errors encountered during query processing.
 //
 // Function Behavior:
 // - The function begins by unmarshalling the query's JSON into a QueryModel object.
+// - Annotation queries, identified by their Grafana query type, are mapped to annotation fields by `processAnnotations`.
 // - It identifies the query type (Log Metrics Time Series, Log Metrics No Interval, or Log Records) based on the query text.
 // - Depending on the query type, it calls the appropriate method to process the log data (e.g., `processLogMetricTimeSeries`, `processLogMetrics`, or `processLogRecords`).
+// - The query runs within the timeout of the query, or of the datasource. Data collected before the deadline is returned with a warning notice.
 // - If an error occurs during processing, it is returned in the response. The function ensures proper handling of different query types to return the correct data format for the client.
-func (ocidx *OCIDatasource) query(ctx context.Context, pCtx backend.PluginContext, query backend.DataQuery) (map[string]*DataFieldElements, backend.DataResponse) {
+func (ocidx *OCIDatasource) query(ctx context.Context, pCtx backend.PluginContext, query backend.DataQuery) (map[string]*DataFieldElements, []data.Notice, backend.DataResponse) {
 	backend.Logger.Debug("plugin.query", "query", "query initiated for "+query.RefID)
 	// Creating the Data response for query
 	response := backend.DataResponse{}
@@ -39,10 +46,25 @@ func (ocidx *OCIDatasource) query(ctx context.Context, pCtx backend.PluginContex
 	qm := &models.QueryModel{}
 	response.Error = json.Unmarshal(query.JSON, &qm)
 	if response.Error != nil {
-		return nil, response
+		return nil, nil, response
 	}
 
 	takey := ocidx.GetTenancyAccessKey(qm.TenancyOCID)
+	// Queries evaluated by alert rules do not go through the frontend, so the tenancy must be validated here
+	if len(takey) == 0 {
+		response.Error = fmt.Errorf("invalid tenancy: %s", qm.TenancyOCID)
+		return nil, nil, response
+	}
+	if response.Error = checkSessionToken(ocidx.tenancyAccess[takey].config); response.Error != nil {
+		return nil, nil, response
+	}
+
+	timeout := queryTimeout(qm.Timeout, ocidx.settings.QueryTimeout)
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
 
 	logQueryType := ocidx.identifyQueryType(qm.QueryText)
 
@@ -51,7 +73,19 @@ func (ocidx *OCIDatasource) query(ctx context.Context, pCtx backend.PluginContex
 	toMs := query.TimeRange.To.UnixNano() / int64(time.Millisecond)
 	var mFieldData = make(map[string]*DataFieldElements)
 
-	if logQueryType == QueryType_LogMetrics_TimeSeries {
+	// The log volume histogram of a log records query is computed as a count time series
+	if qm.LogVolume && logQueryType == QueryType_LogRecords {
+		qm.QueryText = ocidx.logVolumeQuery(ctx, qm, fromMs, toMs, takey)
+		qm.FillMode = constants.FillMode_Zero
+		logQueryType = QueryType_LogMetrics_TimeSeries
+		ocidx.logger.Debug("Log records query rewritten for the log volume", "refId", query.RefID, "query", qm.QueryText)
+	}
+
+	if query.QueryType == constants.QueryType_Annotation {
+		ocidx.logger.Debug("Logging query will return annotations for the specified time interval", "refId", query.RefID)
+		// Call method that maps log records to annotation fields
+		mFieldData, processErr = ocidx.processAnnotations(ctx, query, qm, fromMs, toMs, mFieldData, takey)
+	} else if logQueryType == QueryType_LogMetrics_TimeSeries {
 		ocidx.logger.Debug("Logging query WILL return numeric data over intervals", "refId", query.RefID)
 		// Call method that parses log metric results and produces the required field definitions
 		mFieldData, processErr = ocidx.processLogMetricTimeSeries(ctx, query, qm, fromMs, toMs, mFieldData, takey)
@@ -65,10 +99,26 @@ func (ocidx *OCIDatasource) query(ctx context.Context, pCtx backend.PluginContex
 		// Call method that parses log record results and produces the required field definitions
 		mFieldData, processErr = ocidx.processLogRecords(ctx, query, qm, fromMs, toMs, mFieldData, takey)
 	}
+	var notices []data.Notice
+	var conversion *valueConversionError
+	if errors.As(processErr, &conversion) {
+		ocidx.logger.Warn("Values could not be converted", "refId", query.RefID, "reason", conversion.notice.Text)
+		notices = append(notices, conversion.notice)
+		processErr = conversion.err
+	}
+	var partial *partialResultsError
+	if errors.As(processErr, &partial) {
+		ocidx.logger.Warn("Returning partial results", "refId", query.RefID, "reason", partial.reason)
+		notices = append(notices, partial.notice())
+		processErr = nil
+	}
 	if processErr != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			processErr = timeoutError(timeout, processErr)
+		}
 		response.Error = processErr
-		return nil, response
+		return nil, nil, response
 	}
 
-	return mFieldData, response
+	return mFieldData, notices, response
 }
@@ -24,11 +24,44 @@ type queryRequest struct {
 	Tenancy   string `json:"tenancy"`   // The OCID of the tenancy
 	Region    string `json:"region"`    // The region of the tenancy
 	Query     string `json:"getquery"`  // The query to be executed
-	Field     string `json:"field"`     // Specific field for the query
+	Field     string `json:"field"`     // The field of the values, or "<text field>, <value field>"
+	Regex     string `json:"regex"`     // The regex of the texts of the values to keep (optional)
 	TimeStart int64  `json:"timeStart"` // The start timestamp of the time range for the query (in milliseconds)
 	TimeEnd   int64  `json:"timeEnd"`   // The end timestamp of the time range for the query (in milliseconds)
 }
 
+// liveTailRequest defines the structure for requests of the live tail channel of a query.
+type liveTailRequest struct {
+	Tenancy string `json:"tenancy"`     // The tenancy of the query
+	Query   string `json:"searchQuery"` // The log records query, with the template variables replaced
+}
+
+// logContextRequest defines the structure for requests of the log records surrounding a log record.
+type logContextRequest struct {
+	Tenancy       string `json:"tenancy"`       // The OCID of the tenancy
+	LogId         string `json:"logId"`         // The OCID of the log of the record, from oracle.logid
+	LogGroupId    string `json:"logGroupId"`    // The OCID of the log group of the record, from oracle.loggroupid (optional)
+	CompartmentId string `json:"compartmentId"` // The OCID of the compartment of the record, from oracle.compartmentid (optional)
+	RecordId      string `json:"id"`            // The id of the record, excluded from the results (optional)
+	Time          int64  `json:"time"`          // The timestamp of the record (in milliseconds)
+	Limit         int    `json:"limit"`         // The number of records to return before and after the record
+}
+
+// scopeRequest defines the structure for requests listing the compartments, log groups or logs of a tenancy.
+type scopeRequest struct {
+	Tenancy     string `json:"tenancy"`     // The OCID of the tenancy
+	Compartment string `json:"compartment"` // The OCID of the compartment of the log groups, the whole tenancy if empty (optional)
+	LogGroup    string `json:"logGroup"`    // The OCID of the log group of the logs
+	Region      string `json:"region"`      // The region of the log groups or logs, the region of the tenancy if empty (optional)
+}
+
+// diagnosticsRequest defines the structure for requests of the IAM diagnostics of a tenancy.
+type diagnosticsRequest struct {
+	Tenancy     string `json:"tenancy"`     // The OCID of the tenancy
+	Compartment string `json:"compartment"` // The OCID of the compartment, the root compartment if empty (optional)
+	LogGroup    string `json:"logGroup"`    // The OCID of the log group (optional)
+}
+
 // registerRoutes registers the HTTP routes and their corresponding handler functions.
 // Parameters:
 //   - mux: *http.ServeMux - The multiplexer that routes HTTP requests to the appropriate handlers.
@@ -36,6 +69,13 @@ func (ocidx *OCIDatasource) registerRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/tenancies", ocidx.GetTenanciesHandler)
 	mux.HandleFunc("/regions", ocidx.GetRegionsHandler)
 	mux.HandleFunc("/getquery", ocidx.GetQueryHandler)
+	mux.HandleFunc("/compartments", ocidx.GetCompartmentsHandler)
+	mux.HandleFunc("/loggroups", ocidx.GetLogGroupsHandler)
+	mux.HandleFunc("/logs", ocidx.GetLogsHandler)
+	mux.HandleFunc("/logcontext", ocidx.GetLogContextHandler)
+	mux.HandleFunc("/livetail", ocidx.LiveTailChannelHandler)
+	mux.HandleFunc("/revalidate", ocidx.RevalidateCredentialsHandler)
+	mux.HandleFunc("/diagnostics", ocidx.DiagnosticsHandler)
 }
 
 // GetTenanciesHandler handles GET requests for retrieving a list of tenancies.
@@ -84,7 +124,7 @@ func (ocidx *OCIDatasource) GetRegionsHandler(rw http.ResponseWriter, req *http.
 // GetQueryHandler handles POST requests for querying logs based on the provided parameters.
 // Parameters:
 //   - rw: http.ResponseWriter - The response writer to send the response to the client.
-//   - req: *http.Request - The incoming HTTP request containing the query details (Tenancy, Region, Query, Field, TimeStart, TimeEnd).
+//   - req: *http.Request - The incoming HTTP request containing the query details (Tenancy, Region, Query, Field, Regex, TimeStart, TimeEnd).
 func (ocidx *OCIDatasource) GetQueryHandler(rw http.ResponseWriter, req *http.Request) {
 	if req.Method != http.MethodPost {
 		respondWithError(rw, http.StatusMethodNotAllowed, "Invalid method", nil)
@@ -99,7 +139,7 @@ func (ocidx *OCIDatasource) GetQueryHandler(rw http.ResponseWriter, req *http.Re
 	}
 
 	// Execute the query and fetch results based on the parameters
-	resp, err := ocidx.getLogs(req.Context(), rr.Tenancy, rr.Query, rr.Field, rr.TimeStart, rr.TimeEnd)
+	resp, err := ocidx.getLogs(req.Context(), rr.Tenancy, rr.Query, rr.Field, rr.Regex, rr.TimeStart, rr.TimeEnd)
 	if err != nil {
 		backend.Logger.Error("plugin.resource_handler", "GetQueryHandler", err)
 		respondWithError(rw, http.StatusBadRequest, "Could not run query", err)
@@ -115,6 +155,191 @@ func (ocidx *OCIDatasource) GetQueryHandler(rw http.ResponseWriter, req *http.Re
 	writeResponse(rw, resp)
 }
 
+// GetCompartmentsHandler handles POST requests for retrieving the compartments of a tenancy.
+// Parameters:
+//   - rw: http.ResponseWriter - The response writer to send the response to the client.
+//   - req: *http.Request - The incoming HTTP request containing the tenancy OCID in the body.
+func (ocidx *OCIDatasource) GetCompartmentsHandler(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		respondWithError(rw, http.StatusMethodNotAllowed, "Invalid method", nil)
+		return
+	}
+
+	var rr scopeRequest
+	if err := jsoniter.NewDecoder(req.Body).Decode(&rr); err != nil {
+		backend.Logger.Error("plugin.resource_handler", "GetCompartmentsHandler", err)
+		respondWithError(rw, http.StatusBadRequest, "Failed to read request body", err)
+		return
+	}
+
+	resp, err := ocidx.GetCompartments(req.Context(), rr.Tenancy)
+	if err != nil {
+		backend.Logger.Error("plugin.resource_handler", "GetCompartmentsHandler", err)
+		respondWithError(rw, http.StatusBadRequest, "Could not read compartments", err)
+		return
+	}
+	backend.Logger.Debug("plugin.resource_handler", "GetCompartmentsHandler", len(resp))
+	writeResponse(rw, resp)
+}
+
+// GetLogGroupsHandler handles POST requests for retrieving the log groups of a compartment.
+// Parameters:
+//   - rw: http.ResponseWriter - The response writer to send the response to the client.
+//   - req: *http.Request - The incoming HTTP request containing the tenancy and compartment OCIDs in the body.
+func (ocidx *OCIDatasource) GetLogGroupsHandler(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		respondWithError(rw, http.StatusMethodNotAllowed, "Invalid method", nil)
+		return
+	}
+
+	var rr scopeRequest
+	if err := jsoniter.NewDecoder(req.Body).Decode(&rr); err != nil {
+		backend.Logger.Error("plugin.resource_handler", "GetLogGroupsHandler", err)
+		respondWithError(rw, http.StatusBadRequest, "Failed to read request body", err)
+		return
+	}
+
+	resp, err := ocidx.GetLogGroups(req.Context(), rr.Tenancy, rr.Compartment, rr.Region)
+	if err != nil {
+		backend.Logger.Error("plugin.resource_handler", "GetLogGroupsHandler", err)
+		respondWithError(rw, http.StatusBadRequest, "Could not read log groups", err)
+		return
+	}
+	backend.Logger.Debug("plugin.resource_handler", "GetLogGroupsHandler", len(resp))
+	writeResponse(rw, resp)
+}
+
+// GetLogsHandler handles POST requests for retrieving the logs of a log group.
+// Parameters:
+//   - rw: http.ResponseWriter - The response writer to send the response to the client.
+//   - req: *http.Request - The incoming HTTP request containing the tenancy and log group OCIDs in the body.
+func (ocidx *OCIDatasource) GetLogsHandler(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		respondWithError(rw, http.StatusMethodNotAllowed, "Invalid method", nil)
+		return
+	}
+
+	var rr scopeRequest
+	if err := jsoniter.NewDecoder(req.Body).Decode(&rr); err != nil {
+		backend.Logger.Error("plugin.resource_handler", "GetLogsHandler", err)
+		respondWithError(rw, http.StatusBadRequest, "Failed to read request body", err)
+		return
+	}
+	if rr.LogGroup == "" {
+		respondWithError(rw, http.StatusBadRequest, "Log group OCID is required", nil)
+		return
+	}
+
+	resp, err := ocidx.GetLogGroupLogs(req.Context(), rr.Tenancy, rr.LogGroup, rr.Region)
+	if err != nil {
+		backend.Logger.Error("plugin.resource_handler", "GetLogsHandler", err)
+		respondWithError(rw, http.StatusBadRequest, "Could not read logs", err)
+		return
+	}
+	backend.Logger.Debug("plugin.resource_handler", "GetLogsHandler", len(resp))
+	writeResponse(rw, resp)
+}
+
+// GetLogContextHandler handles POST requests for retrieving the log records surrounding a log record.
+// Parameters:
+//   - rw: http.ResponseWriter - The response writer to send the response to the client.
+//   - req: *http.Request - The incoming HTTP request containing the log record details (Tenancy, LogId, Time, Limit).
+func (ocidx *OCIDatasource) GetLogContextHandler(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		respondWithError(rw, http.StatusMethodNotAllowed, "Invalid method", nil)
+		return
+	}
+
+	var rr logContextRequest
+	if err := jsoniter.NewDecoder(req.Body).Decode(&rr); err != nil {
+		backend.Logger.Error("plugin.resource_handler", "GetLogContextHandler", err)
+		respondWithError(rw, http.StatusBadRequest, "Failed to read request body", err)
+		return
+	}
+	if rr.LogId == "" || rr.Time == 0 {
+		respondWithError(rw, http.StatusBadRequest, "Log OCID and time are required", nil)
+		return
+	}
+	if err := validateLogContextRequest(rr); err != nil {
+		respondWithError(rw, http.StatusBadRequest, "Invalid log context request", err)
+		return
+	}
+
+	resp, err := ocidx.getLogContext(req.Context(), rr)
+	if err != nil {
+		backend.Logger.Error("plugin.resource_handler", "GetLogContextHandler", err)
+		respondWithError(rw, http.StatusBadRequest, "Could not read log context", err)
+		return
+	}
+	backend.Logger.Debug("plugin.resource_handler", "GetLogContextHandler", len(resp.Before)+len(resp.After))
+	writeResponse(rw, resp)
+}
+
+// RevalidateCredentialsHandler handles POST requests to read again the credentials of the tenancies, such as
+// rotated API keys of an OCI config file or a renewed OCI CLI session, and test them.
+// Parameters:
+//   - rw: http.ResponseWriter - The response writer to send the response to the client.
+//   - req: *http.Request - The incoming HTTP request, without body.
+func (ocidx *OCIDatasource) RevalidateCredentialsHandler(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		respondWithError(rw, http.StatusMethodNotAllowed, "Invalid method", nil)
+		return
+	}
+
+	resp := ocidx.revalidateCredentials(req.Context())
+	backend.Logger.Debug("plugin.resource_handler", "RevalidateCredentialsHandler", resp.Status)
+	writeResponse(rw, resp)
+}
+
+// DiagnosticsHandler handles POST requests probing the OCI operations used by the plugin for a tenancy,
+// and suggesting the IAM policy statements granting the failing operations.
+// Parameters:
+//   - rw: http.ResponseWriter - The response writer to send the response to the client.
+//   - req: *http.Request - The incoming HTTP request containing the tenancy, compartment and log group to probe.
+func (ocidx *OCIDatasource) DiagnosticsHandler(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		respondWithError(rw, http.StatusMethodNotAllowed, "Invalid method", nil)
+		return
+	}
+
+	var rr diagnosticsRequest
+	if err := jsoniter.NewDecoder(req.Body).Decode(&rr); err != nil {
+		backend.Logger.Error("plugin.resource_handler", "DiagnosticsHandler", err)
+		respondWithError(rw, http.StatusBadRequest, "Failed to read request body", err)
+		return
+	}
+
+	resp, err := ocidx.RunDiagnostics(req.Context(), rr)
+	if err != nil {
+		backend.Logger.Error("plugin.resource_handler", "DiagnosticsHandler", err)
+		respondWithError(rw, http.StatusBadRequest, "Could not run diagnostics", err)
+		return
+	}
+	backend.Logger.Debug("plugin.resource_handler", "DiagnosticsHandler", resp.Failing)
+	writeResponse(rw, resp)
+}
+
+// LiveTailChannelHandler handles POST requests for the path of the live tail channel of a query, so that
+// the channel path subscribed by the query editor is the one checked by SubscribeStream.
+// Parameters:
+//   - rw: http.ResponseWriter - The response writer to send the response to the client.
+//   - req: *http.Request - The incoming HTTP request containing the tenancy and the query.
+func (ocidx *OCIDatasource) LiveTailChannelHandler(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		respondWithError(rw, http.StatusMethodNotAllowed, "Invalid method", nil)
+		return
+	}
+
+	var rr liveTailRequest
+	if err := jsoniter.NewDecoder(req.Body).Decode(&rr); err != nil {
+		backend.Logger.Error("plugin.resource_handler", "LiveTailChannelHandler", err)
+		respondWithError(rw, http.StatusBadRequest, "Failed to read request body", err)
+		return
+	}
+
+	writeResponse(rw, models.LiveTailChannel{Path: liveTailPath(rr.Tenancy, rr.Query)})
+}
+
 // writeResponse writes a successful JSON response to the http.ResponseWriter.
 //
 // Parameters:
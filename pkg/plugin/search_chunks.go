@@ -0,0 +1,246 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/oracle/oci-grafana-logs/pkg/plugin/constants"
+)
+
+// Sort command of a log records query that keeps the records in time order
+var reSortByDatetime = regexp.MustCompile(`^(?i)sort\s+by\s+datetime(?:\s+(asc|desc))?$`)
+
+// Pipeline commands whose results change when the time range is searched in chunks, e.g. the first 10
+// records of each chunk are not the first 10 records of the time range
+var reUnchunkableCommand = regexp.MustCompile(`^(?i)(sort|head|tail|limit|dedup|summarize|eventstats|stats|top|rare|cluster|link)\b`)
+
+// searchChunk is a sub-range of the time range of a log records query, searched on its own.
+type searchChunk struct {
+	fromMs  int64
+	toMs    int64
+	records []map[string]interface{}
+	err     error
+	done    bool
+	cancel  context.CancelFunc
+}
+
+// chunkableQuery tells whether the time range of a log records query can be searched in chunks stitched
+// in time order, i.e. the query does not sort on another field than the time and does not limit or
+// aggregate its results, and whether the records are returned newest first.
+//
+// Parameters:
+//   - searchQuery: The log records query.
+//
+// Returns:
+//   - bool: Whether the query can be searched in chunks.
+//   - bool: Whether the records are sorted newest first.
+func chunkableQuery(searchQuery string) (bool, bool) {
+	descending := false
+	for _, command := range splitQueryPipeline(searchQuery)[1:] {
+		if matches := reSortByDatetime.FindStringSubmatch(command); matches != nil {
+			descending = strings.EqualFold(matches[1], "desc")
+			continue
+		}
+		if reUnchunkableCommand.MatchString(command) {
+			return false, false
+		}
+	}
+	return true, descending
+}
+
+// searchChunkSize returns the size of the time-range chunks of the log records queries of the datasource.
+//
+// Returns:
+//   - time.Duration: The chunk size, 0 when the time range is not split.
+func (o *OCIDatasource) searchChunkSize() time.Duration {
+	switch {
+	case o.settings.SearchChunkSize < 0:
+		return 0
+	case o.settings.SearchChunkSize == 0:
+		return constants.DefaultSearchChunkSize * time.Minute
+	default:
+		return time.Duration(o.settings.SearchChunkSize) * time.Minute
+	}
+}
+
+// splitSearchRange splits the time range of a log records query into chunks, in the order of the records
+// of the query: oldest first, or newest first for a query sorted by descending time. The chunks are made
+// larger when the time range would otherwise be split into more than MaxSearchChunks chunks.
+//
+// Parameters:
+//   - fromMs: The start time in milliseconds since Unix epoch.
+//   - toMs: The end time in milliseconds since Unix epoch.
+//   - chunkSize: The size of the chunks, 0 to search the time range as a whole.
+//   - descending: Whether the records are sorted newest first.
+//
+// Returns:
+//   - []*searchChunk: The chunks, nil when the time range is not larger than one chunk.
+func splitSearchRange(fromMs int64, toMs int64, chunkSize time.Duration, descending bool) []*searchChunk {
+	sizeMs := chunkSize.Milliseconds()
+	if sizeMs <= 0 || toMs-fromMs <= sizeMs {
+		return nil
+	}
+	count := (toMs - fromMs + sizeMs - 1) / sizeMs
+	if count > constants.MaxSearchChunks {
+		count = constants.MaxSearchChunks
+		sizeMs = (toMs - fromMs + count - 1) / count
+	}
+
+	// The bounds of the time range are inclusive, so a chunk ends one millisecond before the next one
+	// starts and the last chunk ends on the end of the time range
+	chunks := make([]*searchChunk, 0, count)
+	for i := int64(0); i < count; i++ {
+		start := fromMs + i*sizeMs
+		end := min(start+sizeMs-1, toMs)
+		if i == count-1 {
+			end = toMs
+		}
+		chunks = append(chunks, &searchChunk{fromMs: start, toMs: end})
+	}
+	if descending {
+		for i, j := 0, len(chunks)-1; i < j; i, j = i+1, j-1 {
+			chunks[i], chunks[j] = chunks[j], chunks[i]
+		}
+	}
+	return chunks
+}
+
+// processChunkedLogRecords searches the chunks of the time range of a log records query in parallel and
+// stitches their log records in the order of the chunks. Once the chunks stitched so far hold the maximum
+// number of log records of a query, the later chunks are not searched, so that a query sorted newest first
+// only searches the most recent chunks. A failing chunk fails the query, or ends the stitched records when
+// the datasource returns partial results.
+//
+// Parameters:
+//   - ctx: The context for the request execution.
+//   - queryPanelId: The panel of the query, for logging.
+//   - queryRefId: The reference ID of the query.
+//   - searchQuery: The log records query.
+//   - chunks: The chunks of the time range, see splitSearchRange.
+//   - mFieldDefns: A map to store extracted log data fields and their definitions.
+//   - takey: The tenancy key for accessing the appropriate OCI client.
+//
+// Returns:
+//   - map[string]*DataFieldElements: The processed log data fields.
+//   - error: An error if a chunk fails, a *partialResultsError when the stitched records are partial.
+func (o *OCIDatasource) processChunkedLogRecords(ctx context.Context, queryPanelId string, queryRefId string, searchQuery string,
+	chunks []*searchChunk, mFieldDefns map[string]*DataFieldElements, takey string) (map[string]*DataFieldElements, error) {
+
+	maxRecords := numMaxResults - 1
+	o.logger.Debug("Searching log records in time range chunks", "panelId", queryPanelId, "refId", queryRefId,
+		"chunks", len(chunks))
+
+	// Chunks from limit on are not searched: they follow a failing chunk or the row cap is reached before them
+	var mu sync.Mutex
+	next, limit := 0, len(chunks)
+	shrinkLimit := func(newLimit int) {
+		if newLimit >= limit {
+			return
+		}
+		limit = newLimit
+		for _, chunk := range chunks[limit:] {
+			if chunk.cancel != nil {
+				chunk.cancel()
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < min(constants.MaxParallelSearchChunks, len(chunks)); worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				mu.Lock()
+				if next >= limit || ctx.Err() != nil {
+					mu.Unlock()
+					return
+				}
+				chunk := chunks[next]
+				next++
+				chunkCtx, cancel := context.WithCancel(ctx)
+				chunk.cancel = cancel
+				mu.Unlock()
+
+				records, err := o.searchLogRecords(chunkCtx, takey, searchQuery, time.UnixMilli(chunk.fromMs), time.UnixMilli(chunk.toMs), maxRecords)
+				cancel()
+
+				mu.Lock()
+				chunk.records, chunk.err, chunk.done = records, err, true
+				count := 0
+				for i, stitched := range chunks[:limit] {
+					if !stitched.done {
+						break
+					}
+					if stitched.err != nil {
+						shrinkLimit(i + 1)
+						break
+					}
+					if count += len(stitched.records); count >= maxRecords {
+						shrinkLimit(i + 1)
+						break
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	logRecords := make([]map[string]interface{}, 0)
+	stitched := 0
+	var failure error
+	for _, chunk := range chunks[:limit] {
+		if !chunk.done {
+			failure = ctx.Err()
+			break
+		}
+		if chunk.err != nil {
+			failure = chunk.err
+			break
+		}
+		logRecords = append(logRecords, chunk.records...)
+		stitched++
+	}
+	if len(logRecords) > maxRecords {
+		logRecords = logRecords[:maxRecords]
+	}
+
+	for index, logRecord := range logRecords {
+		o.setLogRecordFields(mFieldDefns, logRecord, index, queryPanelId, queryRefId)
+	}
+	trimFieldValues(mFieldDefns, len(logRecords))
+
+	if failure == nil {
+		o.logger.Debug("Stitched log records of the time range chunks", "panelId", queryPanelId, "refId", queryRefId,
+			"chunks", stitched, "resultsCount", len(logRecords))
+		return mFieldDefns, nil
+	}
+
+	failing := chunks[stitched]
+	position := fmt.Sprintf("chunk %d of %d (%s to %s)", stitched+1, len(chunks),
+		time.UnixMilli(failing.fromMs).UTC().Format(time.RFC3339), time.UnixMilli(failing.toMs).UTC().Format(time.RFC3339))
+	returned := fmt.Sprintf(", %d log records returned", len(logRecords))
+	if ctx.Err() != nil {
+		return mFieldDefns, interruptedSearch(ctx, stitched, "while searching "+position+returned)
+	}
+
+	errMessage := fmt.Sprintf("processLogRecords Log search operation FAILED, panelId = %s, refId = %s, chunk = %s, err = %s, query = %s%s",
+		queryPanelId, queryRefId, position, failure, searchQuery, permissionHint(errors.Cause(failure), permissionReadLogContent))
+	o.logger.Error(errMessage)
+	if partialErr := o.failedSearch(errors.Cause(failure), stitched, position, returned); partialErr != nil {
+		return mFieldDefns, partialErr
+	}
+	return nil, errors.Wrap(failure, errMessage)
+}
@@ -0,0 +1,94 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/oracle/oci-grafana-logs/pkg/plugin/constants"
+)
+
+func TestChunkableQuery(t *testing.T) {
+	tests := []struct {
+		query          string
+		wantChunkable  bool
+		wantDescending bool
+	}{
+		{query: `search "ocid1.compartment.oc1..a"`, wantChunkable: true},
+		{query: `search "ocid1.compartment.oc1..a" | where level = 'ERROR' | fields data.message`, wantChunkable: true},
+		{query: `search "ocid1.compartment.oc1..a" | sort by datetime`, wantChunkable: true},
+		{query: `search "ocid1.compartment.oc1..a" | sort by datetime asc`, wantChunkable: true},
+		{query: `search "ocid1.compartment.oc1..a" | SORT BY DATETIME DESC`, wantChunkable: true, wantDescending: true},
+		{query: `search "ocid1.compartment.oc1..a" | sort by data.level`},
+		{query: `search "ocid1.compartment.oc1..a" | sort by datetime desc | head 10`},
+		{query: `search "ocid1.compartment.oc1..a" | limit 10`},
+		{query: `search "ocid1.compartment.oc1..a" | dedup data.message`},
+		{query: `search "ocid1.compartment.oc1..a" | summarize count() by level`},
+		{query: `search "ocid1.compartment.oc1..a" | where data.message = 'a | head 1'`, wantChunkable: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			chunkable, descending := chunkableQuery(tt.query)
+			if chunkable != tt.wantChunkable || descending != tt.wantDescending {
+				t.Fatalf("chunkableQuery() = %v, %v, want %v, %v", chunkable, descending, tt.wantChunkable, tt.wantDescending)
+			}
+		})
+	}
+}
+
+func TestSplitSearchRange(t *testing.T) {
+	const hour = int64(time.Hour / time.Millisecond)
+	tests := []struct {
+		name       string
+		fromMs     int64
+		toMs       int64
+		chunkSize  time.Duration
+		descending bool
+		want       [][2]int64
+	}{
+		{name: "no chunk size", fromMs: 0, toMs: 48 * hour, want: nil},
+		{name: "range within one chunk", fromMs: 0, toMs: 24 * hour, chunkSize: 24 * time.Hour, want: nil},
+		{
+			name: "oldest first", fromMs: 0, toMs: 60 * hour, chunkSize: 24 * time.Hour,
+			want: [][2]int64{{0, 24*hour - 1}, {24 * hour, 48*hour - 1}, {48 * hour, 60 * hour}},
+		},
+		{
+			name: "newest first", fromMs: 0, toMs: 60 * hour, chunkSize: 24 * time.Hour, descending: true,
+			want: [][2]int64{{48 * hour, 60 * hour}, {24 * hour, 48*hour - 1}, {0, 24*hour - 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got [][2]int64
+			for _, chunk := range splitSearchRange(tt.fromMs, tt.toMs, tt.chunkSize, tt.descending) {
+				got = append(got, [2]int64{chunk.fromMs, chunk.toMs})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("splitSearchRange() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("at most MaxSearchChunks chunks", func(t *testing.T) {
+		toMs := 100 * 24 * hour
+		chunks := splitSearchRange(0, toMs, 24*time.Hour, false)
+		if len(chunks) != constants.MaxSearchChunks {
+			t.Fatalf("splitSearchRange() returned %d chunks, want %d", len(chunks), constants.MaxSearchChunks)
+		}
+		if chunks[0].fromMs != 0 || chunks[len(chunks)-1].toMs != toMs {
+			t.Fatalf("splitSearchRange() covers %d to %d, want 0 to %d", chunks[0].fromMs, chunks[len(chunks)-1].toMs, toMs)
+		}
+		for i := 1; i < len(chunks); i++ {
+			if chunks[i].fromMs != chunks[i-1].toMs+1 {
+				t.Fatalf("chunk %d starts at %d, want %d", i, chunks[i].fromMs, chunks[i-1].toMs+1)
+			}
+		}
+	})
+}
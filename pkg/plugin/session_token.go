@@ -0,0 +1,318 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/pkg/errors"
+
+	"github.com/oracle/oci-grafana-logs/pkg/plugin/constants"
+)
+
+// sessionTokenProvider is a configuration provider signing requests with the security token of an
+// OCI CLI session, as created by `oci session authenticate`.
+//
+// The tenancy, region, private key and fingerprint are read from the profile of the OCI config file by the
+// embedded SDK provider. The security token is kept in memory: it is re-read when its file changes, and
+// refreshed against the OCI authentication service shortly before it expires.
+type sessionTokenProvider struct {
+	common.ConfigurationProvider
+
+	configFilePath string
+	profile        string
+	tokenFilePath  string
+	httpClient     *http.Client
+
+	mu           sync.Mutex
+	token        string
+	expiresAt    time.Time
+	tokenModTime time.Time
+}
+
+// sessionTokenKeyProvider signs the refresh request with a fixed security token, so that the refresh
+// does not go through the locked KeyID of the session token provider.
+type sessionTokenKeyProvider struct {
+	common.ConfigurationProvider
+	keyID string
+}
+
+// KeyID returns the key ID made of the fixed security token.
+func (p sessionTokenKeyProvider) KeyID() (string, error) {
+	return p.keyID, nil
+}
+
+// newSessionTokenProvider creates a configuration provider for the session of a profile of the OCI config file.
+//
+// Parameters:
+//   - configFilePath: The path of the OCI config file, "~" is expanded to the home folder.
+//   - profile: The name of the profile holding the session.
+//
+// Returns:
+//   - *sessionTokenProvider: The session token configuration provider.
+//   - error: An error if the profile is not a valid session profile or its security token cannot be read.
+func newSessionTokenProvider(configFilePath string, profile string) (*sessionTokenProvider, error) {
+	if configFilePath == "" {
+		configFilePath = constants.DefaultOCIConfigFilePath
+	}
+	if profile == "" {
+		profile = constants.DefaultOCIConfigProfile
+	}
+
+	profiles, err := readOCIConfigFile(configFilePath)
+	if err != nil {
+		return nil, err
+	}
+	settings, ok := profiles[profile]
+	if !ok {
+		return nil, errors.Errorf("profile %s not found in OCI config file %s", profile, configFilePath)
+	}
+	for _, key := range []string{"security_token_file", "key_file", "fingerprint", "tenancy", "region"} {
+		if settings[key] == "" {
+			return nil, errors.Errorf("profile %s of OCI config file %s has no %s, create the session with `oci session authenticate --profile-name %s`", profile, configFilePath, key, profile)
+		}
+	}
+
+	baseProvider, err := common.ConfigurationProviderForSessionTokenWithProfile(expandHomePath(configFilePath), profile, "")
+	if err != nil {
+		return nil, errors.Wrap(err, "error with session token configuration")
+	}
+
+	p := &sessionTokenProvider{
+		ConfigurationProvider: baseProvider,
+		configFilePath:        configFilePath,
+		profile:               profile,
+		tokenFilePath:         settings["security_token_file"],
+		httpClient:            &http.Client{Timeout: 30 * time.Second},
+	}
+	if err := p.reloadTokenIfChanged(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Refreshable tells the OCI SDK that the credentials of the provider may change over time.
+func (p *sessionTokenProvider) Refreshable() bool {
+	return true
+}
+
+// KeyID returns the key ID used to sign the requests, made of the current security token.
+// The token is refreshed first when it is about to expire.
+//
+// Returns:
+//   - string: The key ID of the session.
+//   - error: An error if the session has expired.
+func (p *sessionTokenProvider) KeyID() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.ensureValidToken(); err != nil {
+		return "", err
+	}
+	return "ST$" + p.token, nil
+}
+
+// Validate checks that the session has not expired, refreshing the token when it is about to expire.
+//
+// Returns:
+//   - error: An error explaining how to renew the session if it has expired.
+func (p *sessionTokenProvider) Validate() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.ensureValidToken()
+}
+
+// ReloadCredentials reads the security token file again, whether it changed or not, and checks that the
+// session has not expired. On failure the previous token is kept.
+//
+// Returns:
+//   - error: An error if the token file cannot be read or the session has expired.
+func (p *sessionTokenProvider) ReloadCredentials() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	token, modTime := p.token, p.tokenModTime
+	p.token = ""
+	if err := p.reloadTokenIfChanged(); err != nil {
+		p.token, p.tokenModTime = token, modTime
+		return err
+	}
+	return p.ensureValidToken()
+}
+
+// ensureValidToken reloads the token if `oci session` rewrote its file, and refreshes it when it expires
+// within the refresh margin. A failed refresh is only logged as long as the current token is still valid.
+// The caller must hold the lock of the provider.
+func (p *sessionTokenProvider) ensureValidToken() error {
+	if err := p.reloadTokenIfChanged(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if !now.Before(p.expiresAt) {
+		return p.expiredError()
+	}
+	if p.expiresAt.Sub(now) < constants.SessionTokenRefreshMargin*time.Second {
+		if err := p.refreshToken(); err != nil {
+			backend.Logger.Warn("plugin.session_token", "refreshToken", "could not refresh the session token", "profile", p.profile, "expiresAt", p.expiresAt, "error", err)
+		}
+	}
+
+	return nil
+}
+
+// expiredError builds the error returned once the session has expired.
+func (p *sessionTokenProvider) expiredError() error {
+	return errors.Errorf("the OCI session of profile %s expired at %s, renew it with `oci session authenticate --profile-name %s`",
+		p.profile, p.expiresAt.UTC().Format(time.RFC3339), p.profile)
+}
+
+// reloadTokenIfChanged reads the security token file when it was modified since it was last read.
+// The caller must hold the lock of the provider.
+func (p *sessionTokenProvider) reloadTokenIfChanged() error {
+	info, err := os.Stat(p.tokenFilePath)
+	if err != nil {
+		return errors.Wrap(err, "can not read security token file of profile "+p.profile)
+	}
+	if p.token != "" && !info.ModTime().After(p.tokenModTime) {
+		return nil
+	}
+
+	content, err := os.ReadFile(p.tokenFilePath)
+	if err != nil {
+		return errors.Wrap(err, "can not read security token file of profile "+p.profile)
+	}
+	token := strings.TrimSpace(string(content))
+	expiresAt, err := securityTokenExpiry(token)
+	if err != nil {
+		return errors.Wrap(err, "invalid security token in profile "+p.profile)
+	}
+
+	p.token = token
+	p.expiresAt = expiresAt
+	p.tokenModTime = info.ModTime()
+	backend.Logger.Debug("plugin.session_token", "reloadTokenIfChanged", "security token loaded", "profile", p.profile, "expiresAt", expiresAt)
+
+	return nil
+}
+
+// refreshToken exchanges the current security token for a new one with the OCI authentication service,
+// as `oci session refresh` does. The new token is written back to the token file on a best effort basis,
+// so that the OCI CLI also sees it. The caller must hold the lock of the provider.
+func (p *sessionTokenProvider) refreshToken() error {
+	region, err := p.Region()
+	if err != nil {
+		return err
+	}
+	endpoint := common.StringToRegion(region).EndpointForTemplate("auth", "https://auth.{region}.{secondLevelDomain}")
+	body, err := jsoniter.Marshal(map[string]string{"currentToken": p.token})
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequest(http.MethodPost, endpoint+"/v1/authentication/refresh", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	request.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+
+	signer := common.DefaultRequestSigner(sessionTokenKeyProvider{ConfigurationProvider: p.ConfigurationProvider, keyID: "ST$" + p.token})
+	if err := signer.Sign(request); err != nil {
+		return errors.Wrap(err, "can not sign session refresh request")
+	}
+
+	response, err := p.httpClient.Do(request)
+	if err != nil {
+		return errors.Wrap(err, "session refresh request failed")
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("session refresh request failed with status %d", response.StatusCode)
+	}
+
+	var refreshed struct {
+		Token string `json:"token"`
+	}
+	if err := jsoniter.NewDecoder(response.Body).Decode(&refreshed); err != nil {
+		return errors.Wrap(err, "can not read session refresh response")
+	}
+	expiresAt, err := securityTokenExpiry(refreshed.Token)
+	if err != nil {
+		return errors.Wrap(err, "invalid refreshed security token")
+	}
+
+	p.token = refreshed.Token
+	p.expiresAt = expiresAt
+	if err := os.WriteFile(p.tokenFilePath, []byte(refreshed.Token), 0600); err != nil {
+		backend.Logger.Warn("plugin.session_token", "refreshToken", "refreshed session token kept in memory only", "profile", p.profile, "error", err)
+	} else if info, err := os.Stat(p.tokenFilePath); err == nil {
+		p.tokenModTime = info.ModTime()
+	}
+	backend.Logger.Debug("plugin.session_token", "refreshToken", "security token refreshed", "profile", p.profile, "expiresAt", expiresAt)
+
+	return nil
+}
+
+// securityTokenExpiry reads the expiry time from the "exp" claim of a security token, which is a JWT.
+//
+// Parameters:
+//   - token: The security token.
+//
+// Returns:
+//   - time.Time: The expiry time of the token.
+//   - error: An error if the token is not a JWT with an expiry time.
+func securityTokenExpiry(token string) (time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, errors.New("security token is not a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "can not decode security token")
+	}
+
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := jsoniter.Unmarshal(payload, &claims); err != nil {
+		return time.Time{}, errors.Wrap(err, "can not decode security token claims")
+	}
+	if claims.Exp == 0 {
+		return time.Time{}, errors.New("security token has no expiry time")
+	}
+
+	return time.Unix(claims.Exp, 0), nil
+}
+
+// checkSessionToken validates the session of a configuration provider before it is used, so that an
+// expired session is reported as such instead of as a failed OCI request. Other providers are always valid.
+//
+// Parameters:
+//   - configProvider: The configuration provider of a tenancy.
+//
+// Returns:
+//   - error: An error if the provider is a session token provider whose session has expired.
+func checkSessionToken(configProvider common.ConfigurationProvider) error {
+	if p, ok := configProvider.(*sessionTokenProvider); ok {
+		return p.Validate()
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+func TestSecurityTokenExpiry(t *testing.T) {
+	jwt := func(claims string) string {
+		return "eyJhbGciOiJSUzI1NiJ9." + base64.RawURLEncoding.EncodeToString([]byte(claims)) + ".c2lnbmF0dXJl"
+	}
+	tests := []struct {
+		name    string
+		token   string
+		want    time.Time
+		wantErr bool
+	}{
+		{name: "expiry claim", token: jwt(`{"sub":"ocid1.user.oc1..a","exp":1700000000}`), want: time.Unix(1700000000, 0)},
+		{name: "padded claims", token: "h." + base64.URLEncoding.EncodeToString([]byte(`{"exp":1700000000}`)) + ".s", want: time.Unix(1700000000, 0)},
+		{name: "no expiry claim", token: jwt(`{"sub":"ocid1.user.oc1..a"}`), wantErr: true},
+		{name: "claims are not JSON", token: jwt(`not json`), wantErr: true},
+		{name: "payload is not base64", token: "h.!!!.s", wantErr: true},
+		{name: "not a JWT", token: "opaque-token", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := securityTokenExpiry(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("securityTokenExpiry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !got.Equal(tt.want) {
+				t.Fatalf("securityTokenExpiry() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
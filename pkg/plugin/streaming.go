@@ -0,0 +1,280 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/pkg/errors"
+
+	"github.com/oracle/oci-grafana-logs/pkg/plugin/constants"
+	"github.com/oracle/oci-grafana-logs/pkg/plugin/models"
+)
+
+// liveTailRecord is a log record pending to be sent to the live tail subscribers.
+type liveTailRecord struct {
+	timestamp time.Time
+	content   map[string]interface{}
+}
+
+// liveTailPath returns the path of the live tail channel of a query, "tail/" followed by the SHA-256
+// digest of the tenancy and the query, so that clients tailing the same query share the same channel
+// while different queries never do.
+//
+// Parameters:
+//   - tenancy: The tenancy of the query, as sent by the query editor.
+//   - query: The log records query, with the template variables replaced.
+//
+// Returns:
+//   - string: The path of the live tail channel.
+func liveTailPath(tenancy string, query string) string {
+	return constants.LiveTailPathPrefix + settingsHash(tenancy, query)
+}
+
+// SubscribeStream is called when a Grafana client subscribes to a live tail channel.
+// The subscription data must hold a log records query for a valid tenancy, and the channel
+// path must be the path of that query, see liveTailPath. Grafana runs a single stream per
+// path, so a path carrying the data of another query would send its records to the other
+// subscribers of the path.
+//
+// Parameters:
+//   - ctx: The context for the request.
+//   - req: The subscription request, carrying the query model as data.
+//
+// Returns:
+//   - The subscription status.
+//   - An error if the request cannot be processed.
+func (o *OCIDatasource) SubscribeStream(ctx context.Context, req *backend.SubscribeStreamRequest) (*backend.SubscribeStreamResponse, error) {
+	backend.Logger.Debug("plugin.streaming", "SubscribeStream", req.Path)
+
+	if !strings.HasPrefix(req.Path, constants.LiveTailPathPrefix) {
+		return &backend.SubscribeStreamResponse{Status: backend.SubscribeStreamStatusNotFound}, nil
+	}
+
+	qm := &models.QueryModel{}
+	if err := json.Unmarshal(req.Data, qm); err != nil {
+		backend.Logger.Error("plugin.streaming", "SubscribeStream", "invalid subscription data: "+err.Error())
+		return &backend.SubscribeStreamResponse{Status: backend.SubscribeStreamStatusNotFound}, nil
+	}
+	if req.Path != liveTailPath(qm.TenancyOCID, qm.QueryText) {
+		backend.Logger.Error("plugin.streaming", "SubscribeStream", "the subscription data does not match the channel "+req.Path)
+		return &backend.SubscribeStreamResponse{Status: backend.SubscribeStreamStatusNotFound}, nil
+	}
+	if len(o.GetTenancyAccessKey(qm.TenancyOCID)) == 0 || o.identifyQueryType(qm.QueryText) != QueryType_LogRecords {
+		backend.Logger.Error("plugin.streaming", "SubscribeStream", "live tail requires a log records query for a valid tenancy")
+		return &backend.SubscribeStreamResponse{Status: backend.SubscribeStreamStatusPermissionDenied}, nil
+	}
+
+	return &backend.SubscribeStreamResponse{Status: backend.SubscribeStreamStatusOK}, nil
+}
+
+// PublishStream is called when a Grafana client publishes to a live tail channel.
+// Live tail channels are read only, so publishing is always denied.
+func (o *OCIDatasource) PublishStream(ctx context.Context, req *backend.PublishStreamRequest) (*backend.PublishStreamResponse, error) {
+	return &backend.PublishStreamResponse{Status: backend.PublishStreamStatusPermissionDenied}, nil
+}
+
+// RunStream polls the OCI Logging service for a live tail channel and pushes the new log records
+// to the subscribed clients. Grafana calls RunStream once per channel and cancels its context when
+// the last subscriber leaves, which stops the polling. The polling also stops when the instance is
+// disposed, with an error so that Grafana runs the stream again on the new instance.
+//
+// Each poll searches a sliding window ending now, so that records ingested late are still caught.
+// The records are searched newest first, so that a poll reaching the maximum number of records per
+// poll drops the oldest records of the window rather than the new ones, and the frame of such a poll
+// carries a warning. Records already sent are recognized using their id and time and are not sent again.
+//
+// Parameters:
+//   - ctx: The context of the stream, cancelled when there are no more subscribers.
+//   - req: The stream request, carrying the query model as data.
+//   - sender: The sender used to push data frames to the subscribers.
+//
+// Returns:
+//   - error: An error if the query model is invalid or does not match the channel, or the frames cannot be sent.
+func (o *OCIDatasource) RunStream(ctx context.Context, req *backend.RunStreamRequest, sender *backend.StreamSender) error {
+	backend.Logger.Debug("plugin.streaming", "RunStream", "starting live tail for "+req.Path)
+
+	qm := &models.QueryModel{}
+	if err := json.Unmarshal(req.Data, qm); err != nil {
+		return err
+	}
+	if req.Path != liveTailPath(qm.TenancyOCID, qm.QueryText) {
+		return errors.New("the live tail query does not match the channel " + req.Path)
+	}
+	takey := o.GetTenancyAccessKey(qm.TenancyOCID)
+	if len(takey) == 0 {
+		return errors.New("invalid tenancy: " + qm.TenancyOCID)
+	}
+
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+	stopOnDispose := context.AfterFunc(o.streams, stop)
+	defer stopOnDispose()
+
+	searchQuery := withSortClause(qm.QueryText, constants.SortDirection_Desc)
+	ticker := time.NewTicker(time.Duration(constants.LiveTailPollInterval) * time.Second)
+	defer ticker.Stop()
+
+	// Keys of the records already sent, with their time so they can be forgotten once they
+	// are out of the search window
+	mSentRecords := make(map[string]time.Time)
+
+	for {
+		start, end := liveTailSearchWindow(time.Now())
+
+		logRecords, err := o.searchLogRecords(ctx, takey, searchQuery, start, end, constants.LiveTailMaxRecordsPerPoll)
+		if err != nil {
+			if ctx.Err() != nil {
+				return o.streamStopped(req.Path)
+			}
+			// Keep the stream alive, the next poll may succeed
+			o.logger.Error("Live tail log search operation FAILED", "path", req.Path, "error", err)
+		} else {
+			newRecords := newLiveTailRecords(logRecords, mSentRecords, start)
+
+			capped := len(logRecords) >= constants.LiveTailMaxRecordsPerPoll
+			if capped {
+				o.logger.Warn("Live tail poll reached the maximum number of log records", "path", req.Path,
+					"maxRecords", constants.LiveTailMaxRecordsPerPoll)
+			}
+
+			if len(newRecords) > 0 || capped {
+				frame := liveTailFrame(newRecords)
+				if capped {
+					frame.AppendNotices(data.Notice{
+						Severity: data.NoticeSeverityWarning,
+						Text: fmt.Sprintf("The live tail search returned the maximum of %d log records of the last %s, older log records may have been dropped",
+							constants.LiveTailMaxRecordsPerPoll, end.Sub(start)),
+					})
+				}
+				if err := sender.SendFrame(frame, data.IncludeAll); err != nil {
+					o.logger.Error("Unable to send live tail frame", "path", req.Path, "error", err)
+					return err
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return o.streamStopped(req.Path)
+		case <-ticker.C:
+		}
+	}
+}
+
+// liveTailSearchWindow returns the time range searched by a live tail poll: the last LiveTailWindow
+// seconds, so that the records ingested late are still caught by the next polls.
+//
+// Parameters:
+//   - now: The time of the poll.
+//
+// Returns:
+//   - time.Time: The start of the time range, in UTC.
+//   - time.Time: The end of the time range, in UTC.
+func liveTailSearchWindow(now time.Time) (time.Time, time.Time) {
+	end := now.UTC()
+	return end.Add(-time.Duration(constants.LiveTailWindow) * time.Second), end
+}
+
+// newLiveTailRecords returns the log records of a poll that were not sent yet, sorted by time, and
+// records them as sent. A record is recognized by its id and time, and records without a valid time
+// are skipped. The records sent before the start of the window are forgotten, since the next polls
+// cannot return them anymore.
+//
+// Parameters:
+//   - logRecords: The log records returned by the poll.
+//   - sent: The keys of the records already sent, with their time, updated in place.
+//   - start: The start of the time range searched by the poll.
+//
+// Returns:
+//   - []liveTailRecord: The new log records, oldest first.
+func newLiveTailRecords(logRecords []map[string]interface{}, sent map[string]time.Time, start time.Time) []liveTailRecord {
+	newRecords := make([]liveTailRecord, 0)
+	for _, logRecord := range logRecords {
+		timeValue, _ := lookupLogField(logRecord, constants.LogSearchResultsField_Time)
+		timestamp, err := parseLogTime(timeValue)
+		if err != nil {
+			continue
+		}
+		idValue, _ := lookupLogField(logRecord, "id")
+		key := logFieldString(idValue) + "/" + timestamp.Format(time.RFC3339Nano)
+		if _, ok := sent[key]; ok {
+			continue
+		}
+		sent[key] = timestamp
+		newRecords = append(newRecords, liveTailRecord{timestamp: timestamp, content: logRecord})
+	}
+	sort.SliceStable(newRecords, func(i, j int) bool { return newRecords[i].timestamp.Before(newRecords[j].timestamp) })
+
+	for key, timestamp := range sent {
+		if timestamp.Before(start) {
+			delete(sent, key)
+		}
+	}
+	return newRecords
+}
+
+// streamStopped returns the result of a live tail stream whose context is done: nil when the last
+// subscriber left, and an error when the instance was disposed, so that Grafana runs it again.
+//
+// Parameters:
+//   - path: The path of the live tail channel.
+//
+// Returns:
+//   - error: An error if the instance was disposed.
+func (o *OCIDatasource) streamStopped(path string) error {
+	if o.streams.Err() != nil {
+		backend.Logger.Debug("plugin.streaming", "RunStream", "instance disposed, stopping live tail for "+path)
+		return errors.New("live tail stopped, the datasource instance was disposed")
+	}
+	backend.Logger.Debug("plugin.streaming", "RunStream", "stopping live tail for "+path)
+	return nil
+}
+
+// liveTailFrame builds the data frame pushed to live tail subscribers. The frame always has the
+// same fields so that Grafana appends the successive frames to the same buffer.
+//
+// Parameters:
+//   - records: The new log records, sorted by time.
+//
+// Returns:
+//   - The data frame holding the log records.
+func liveTailFrame(records []liveTailRecord) *data.Frame {
+	timestamps := make([]time.Time, len(records))
+	ids := make([]string, len(records))
+	sources := make([]string, len(records))
+	types := make([]string, len(records))
+	logData := make([]string, len(records))
+	oracle := make([]string, len(records))
+
+	for i, record := range records {
+		timestamps[i] = record.timestamp
+		ids[i] = logFieldString(record.content["id"])
+		sources[i] = logFieldString(record.content["source"])
+		types[i] = logFieldString(record.content["type"])
+		logData[i] = logFieldString(record.content[constants.LogSearchResultsField_Data])
+		oracle[i] = logFieldString(record.content[constants.LogSearchResultsField_Oracle])
+	}
+
+	frame := data.NewFrame("live",
+		data.NewField(constants.LogSearchResponseField_timestamp, nil, timestamps),
+		data.NewField("id", nil, ids),
+		data.NewField("source", nil, sources),
+		data.NewField("type", nil, types),
+		data.NewField(constants.LogSearchResultsField_Data, nil, logData),
+		data.NewField(constants.LogSearchResultsField_Oracle, nil, oracle),
+	)
+	frame.SetMeta(&data.FrameMeta{PreferredVisualization: data.VisTypeLogs})
+
+	return frame
+}
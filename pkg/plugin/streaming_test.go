@@ -0,0 +1,199 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+
+	"github.com/oracle/oci-grafana-logs/pkg/plugin/constants"
+	"github.com/oracle/oci-grafana-logs/pkg/plugin/models"
+)
+
+func TestLiveTailPath(t *testing.T) {
+	path := liveTailPath("DEFAULT/", `search "ocid1.compartment.oc1..a"`)
+	if !regexp.MustCompile(`^tail/[0-9a-f]{64}$`).MatchString(path) {
+		t.Fatalf("liveTailPath() = %q, want tail/ followed by a SHA-256 digest", path)
+	}
+	if again := liveTailPath("DEFAULT/", `search "ocid1.compartment.oc1..a"`); again != path {
+		t.Fatalf("liveTailPath() = %q then %q, want a stable path", path, again)
+	}
+
+	tests := []struct {
+		name    string
+		tenancy string
+		query   string
+	}{
+		{name: "other query", tenancy: "DEFAULT/", query: `search "ocid1.compartment.oc1..b"`},
+		{name: "other tenancy", tenancy: "ocid1.tenancy.oc1..b", query: `search "ocid1.compartment.oc1..a"`},
+		{name: "separator moved between the tenancy and the query", tenancy: `DEFAULT/search`, query: ` "ocid1.compartment.oc1..a"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if other := liveTailPath(tt.tenancy, tt.query); other == path {
+				t.Fatalf("liveTailPath(%q, %q) = %q, want a path different from the first query", tt.tenancy, tt.query, other)
+			}
+		})
+	}
+}
+
+func TestSubscribeStream(t *testing.T) {
+	o := &OCIDatasource{
+		logger:        log.DefaultLogger,
+		tenancyAccess: map[string]*logTenancyAccess{SingleTenancyKey: {}},
+		settings:      &models.OCIDatasourceSettings{},
+	}
+	queryA := `search "ocid1.compartment.oc1..a"`
+	queryB := `search "ocid1.compartment.oc1..b"`
+	subscriptionData := func(query string) json.RawMessage {
+		content, _ := json.Marshal(map[string]string{"tenancy": "DEFAULT/", "searchQuery": query})
+		return content
+	}
+
+	tests := []struct {
+		name string
+		path string
+		data json.RawMessage
+		want backend.SubscribeStreamStatus
+	}{
+		{name: "path of the query", path: liveTailPath("DEFAULT/", queryA), data: subscriptionData(queryA), want: backend.SubscribeStreamStatusOK},
+		{name: "path of another query", path: liveTailPath("DEFAULT/", queryA), data: subscriptionData(queryB), want: backend.SubscribeStreamStatusNotFound},
+		{name: "not a live tail path", path: "other/" + liveTailPath("DEFAULT/", queryA), data: subscriptionData(queryA), want: backend.SubscribeStreamStatusNotFound},
+		{name: "invalid data", path: liveTailPath("DEFAULT/", queryA), data: json.RawMessage(`{`), want: backend.SubscribeStreamStatusNotFound},
+		{
+			name: "metric query",
+			path: liveTailPath("DEFAULT/", queryA+" | summarize count() by rounddown(datetime, '5m')"),
+			data: subscriptionData(queryA + " | summarize count() by rounddown(datetime, '5m')"),
+			want: backend.SubscribeStreamStatusPermissionDenied,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := o.SubscribeStream(context.Background(), &backend.SubscribeStreamRequest{Path: tt.path, Data: tt.data})
+			if err != nil {
+				t.Fatalf("SubscribeStream() error = %v", err)
+			}
+			if resp.Status != tt.want {
+				t.Fatalf("SubscribeStream() status = %v, want %v", resp.Status, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunStreamPathMismatch(t *testing.T) {
+	o := &OCIDatasource{
+		logger:        log.DefaultLogger,
+		tenancyAccess: map[string]*logTenancyAccess{SingleTenancyKey: {}},
+		settings:      &models.OCIDatasourceSettings{},
+	}
+	content, _ := json.Marshal(map[string]string{"tenancy": "DEFAULT/", "searchQuery": `search "ocid1.compartment.oc1..b"`})
+	req := &backend.RunStreamRequest{Path: liveTailPath("DEFAULT/", `search "ocid1.compartment.oc1..a"`), Data: content}
+	if err := o.RunStream(context.Background(), req, nil); err == nil {
+		t.Fatalf("RunStream() error = nil, want an error for a query not matching the channel")
+	}
+}
+
+func TestLiveTailSearchWindow(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+	start, end := liveTailSearchWindow(now)
+	if !end.Equal(now) || end.Location() != time.UTC {
+		t.Fatalf("liveTailSearchWindow() end = %v, want %v in UTC", end, now)
+	}
+	if got := end.Sub(start); got != time.Duration(constants.LiveTailWindow)*time.Second {
+		t.Fatalf("liveTailSearchWindow() window = %v, want %ds", got, constants.LiveTailWindow)
+	}
+}
+
+func TestNewLiveTailRecords(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time { return start.Add(time.Duration(seconds) * time.Second) }
+	record := func(id string, seconds int) map[string]interface{} {
+		return map[string]interface{}{"id": id, "time": at(seconds).Format(time.RFC3339Nano)}
+	}
+
+	tests := []struct {
+		name     string
+		sent     map[string]time.Time
+		records  []map[string]interface{}
+		wantIDs  []string
+		wantSent []string
+	}{
+		{
+			name:     "new records sorted by time",
+			sent:     map[string]time.Time{},
+			records:  []map[string]interface{}{record("b", 20), record("a", 10)},
+			wantIDs:  []string{"a", "b"},
+			wantSent: []string{"a/" + at(10).Format(time.RFC3339Nano), "b/" + at(20).Format(time.RFC3339Nano)},
+		},
+		{
+			name:     "records already sent are skipped",
+			sent:     map[string]time.Time{"a/" + at(10).Format(time.RFC3339Nano): at(10)},
+			records:  []map[string]interface{}{record("b", 20), record("a", 10)},
+			wantIDs:  []string{"b"},
+			wantSent: []string{"a/" + at(10).Format(time.RFC3339Nano), "b/" + at(20).Format(time.RFC3339Nano)},
+		},
+		{
+			name:     "same id at another time is a new record",
+			sent:     map[string]time.Time{"a/" + at(10).Format(time.RFC3339Nano): at(10)},
+			records:  []map[string]interface{}{record("a", 30)},
+			wantIDs:  []string{"a"},
+			wantSent: []string{"a/" + at(10).Format(time.RFC3339Nano), "a/" + at(30).Format(time.RFC3339Nano)},
+		},
+		{
+			name:     "duplicates within a poll are sent once",
+			sent:     map[string]time.Time{},
+			records:  []map[string]interface{}{record("a", 10), record("a", 10)},
+			wantIDs:  []string{"a"},
+			wantSent: []string{"a/" + at(10).Format(time.RFC3339Nano)},
+		},
+		{
+			name:     "records without a valid time are skipped",
+			sent:     map[string]time.Time{},
+			records:  []map[string]interface{}{{"id": "a"}, {"id": "b", "time": "yesterday"}},
+			wantIDs:  []string{},
+			wantSent: []string{},
+		},
+		{
+			name:     "records sent before the window are forgotten",
+			sent:     map[string]time.Time{"old/" + at(-10).Format(time.RFC3339Nano): at(-10)},
+			records:  []map[string]interface{}{record("a", 10)},
+			wantIDs:  []string{"a"},
+			wantSent: []string{"a/" + at(10).Format(time.RFC3339Nano)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newLiveTailRecords(tt.records, tt.sent, start)
+			ids := make([]string, 0, len(got))
+			for _, r := range got {
+				ids = append(ids, r.content["id"].(string))
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Fatalf("newLiveTailRecords() ids = %v, want %v", ids, tt.wantIDs)
+			}
+			for i := 1; i < len(got); i++ {
+				if got[i].timestamp.Before(got[i-1].timestamp) {
+					t.Fatalf("newLiveTailRecords() records are not sorted by time: %v", ids)
+				}
+			}
+			if len(tt.sent) != len(tt.wantSent) {
+				t.Fatalf("sent records = %v, want %v", tt.sent, tt.wantSent)
+			}
+			for _, key := range tt.wantSent {
+				if _, ok := tt.sent[key]; !ok {
+					t.Fatalf("sent records = %v, want %v", tt.sent, tt.wantSent)
+				}
+			}
+		})
+	}
+}
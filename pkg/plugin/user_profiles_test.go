@@ -0,0 +1,113 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+
+	"github.com/oracle/oci-grafana-logs/pkg/plugin/models"
+)
+
+func TestUserProfiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings string
+		want     []models.ProfileSettings
+	}{
+		{
+			name:     "legacy profiles",
+			settings: `{"profile0":"DEFAULT","region0":"us-ashburn-1","profile1":"OTHER","region1":"eu-frankfurt-1"}`,
+			want: []models.ProfileSettings{
+				{Name: "DEFAULT", Region: "us-ashburn-1"},
+				{Name: "OTHER", Region: "eu-frankfurt-1"},
+			},
+		},
+		{
+			name:     "legacy profiles stop at the first empty name",
+			settings: `{"profile0":"DEFAULT","region0":"us-ashburn-1","region1":"eu-frankfurt-1","profile2":"IGNORED","region2":"uk-london-1"}`,
+			want:     []models.ProfileSettings{{Name: "DEFAULT", Region: "us-ashburn-1"}},
+		},
+		{
+			name:     "legacy custom region is a dedicated region",
+			settings: `{"profile0":"DEFAULT","region0":"us-ashburn-1","profile1":"DEDICATED","region1":"us-ashburn-1","customregion1":"us-dedicated-1"}`,
+			want: []models.ProfileSettings{
+				{Name: "DEFAULT", Region: "us-ashburn-1"},
+				{Name: "DEDICATED", Region: "us-ashburn-1", Dedicated: true, CustomRegion: "us-dedicated-1"},
+			},
+		},
+		{
+			name:     "no legacy profile",
+			settings: `{"region0":"us-ashburn-1"}`,
+			want:     []models.ProfileSettings{},
+		},
+		{
+			name: "profile list over legacy profiles",
+			settings: `{"profile0":"LEGACY","region0":"uk-london-1","profiles":[` +
+				`{"name":"DEFAULT","region":"us-ashburn-1"},` +
+				`{"name":"DEDICATED","dedicated":true,"customRegion":"us-dedicated-1"}]}`,
+			want: []models.ProfileSettings{
+				{Name: "DEFAULT", Region: "us-ashburn-1"},
+				{Name: "DEDICATED", Dedicated: true, CustomRegion: "us-dedicated-1"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := &models.OCIDatasourceSettings{}
+			if err := settings.Load(backend.DataSourceInstanceSettings{JSONData: []byte(tt.settings)}); err != nil {
+				t.Fatal(err)
+			}
+			if got := settings.UserProfiles(); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("UserProfiles() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadSettingsLegacyProfiles(t *testing.T) {
+	privateKey := string(readTestKey(t, "pkcs1.pem"))
+	secured := map[string]string{}
+	for index := 0; index < 3; index++ {
+		suffix := strconv.Itoa(index)
+		secured["tenancy"+suffix] = "ocid1.tenancy.oc1.." + suffix
+		secured["user"+suffix] = "ocid1.user.oc1.." + suffix
+		secured["fingerprint"+suffix] = testKeyFingerprint
+		secured["privkey"+suffix] = privateKey
+		secured["customdomain"+suffix] = "oci" + suffix + ".example.com"
+	}
+	// The third secured settings belong to a profile after the first empty name
+	settings := `{"tenancymode":"multitenancy",` +
+		`"profile0":"DEFAULT","region0":"us-ashburn-1",` +
+		`"profile1":"DEDICATED","region1":"us-ashburn-1","customregion1":"us-dedicated-1",` +
+		`"profile3":"IGNORED","region3":"uk-london-1"}`
+
+	q, err := OCILoadSettings(backend.DataSourceInstanceSettings{JSONData: []byte(settings), DecryptedSecureJSONData: secured})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantTenancies := map[string]string{"DEFAULT": "ocid1.tenancy.oc1..0", "DEDICATED": "ocid1.tenancy.oc1..1"}
+	if !reflect.DeepEqual(q.tenancyocid, wantTenancies) {
+		t.Fatalf("tenancies = %v, want %v", q.tenancyocid, wantTenancies)
+	}
+	wantUsers := map[string]string{"DEFAULT": "ocid1.user.oc1..0", "DEDICATED": "ocid1.user.oc1..1"}
+	if !reflect.DeepEqual(q.user, wantUsers) {
+		t.Fatalf("users = %v, want %v", q.user, wantUsers)
+	}
+	wantCustomRegions := map[string]string{"DEDICATED": "us-dedicated-1"}
+	if !reflect.DeepEqual(q.customregion, wantCustomRegions) {
+		t.Fatalf("custom regions = %v, want %v", q.customregion, wantCustomRegions)
+	}
+	wantCustomDomains := map[string]string{"DEDICATED": "oci1.example.com"}
+	if !reflect.DeepEqual(q.customdomain, wantCustomDomains) {
+		t.Fatalf("custom domains = %v, want %v", q.customdomain, wantCustomDomains)
+	}
+}
@@ -6,21 +6,21 @@
 package plugin
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
 	"github.com/pkg/errors"
-)
 
-// Prepare format to decode SecureJson
-func transcode(in, out interface{}) {
-	buf := new(bytes.Buffer)
-	json.NewEncoder(buf).Encode(in)
-	json.NewDecoder(buf).Decode(out)
-}
+	"github.com/oracle/oci-grafana-logs/pkg/plugin/constants"
+	"github.com/oracle/oci-grafana-logs/pkg/plugin/models"
+)
 
 // GetTenancyAccessKey retrieves the tenancy access key based on the tenancy mode.
 // If the tenancy mode is "multitenancy", it uses the provided tenancyOCID as the key.
@@ -88,66 +88,255 @@ func FilterMap(inputMap interface{}) (string, error) {
 	return "", errors.New("no valid key found in the map")
 }
 
-// uniqueStrings removes duplicate strings from a slice, returning a new slice
-// containing only unique strings in the order they first appear.
+// rounddownIntervalMs extracts the bucket size used by the rounddown() function of a
+// logging search query, e.g. rounddown(datetime, '5m'), and returns it in milliseconds.
+//
+// Parameters:
+//   - searchQuery: The logging search query containing the rounddown() function.
+//
+// Returns:
+//   - The rounddown interval in milliseconds.
+//   - An error if the query has no rounddown() function or the interval cannot be parsed.
+func rounddownIntervalMs(searchQuery string) (int64, error) {
+	reInterval := regexp.MustCompile(`rounddown\s*\(\s*[^,\)]+,\s*['"]?(?P<value>\d+)\s*(?P<unit>[smhdw])['"]?\s*\)`)
+	matches := reInterval.FindStringSubmatch(searchQuery)
+	if matches == nil {
+		return 0, errors.New("no rounddown interval found in query")
+	}
+
+	value, err := strconv.ParseInt(matches[reInterval.SubexpIndex("value")], 10, 64)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("invalid rounddown interval value: %s", matches[reInterval.SubexpIndex("value")])
+	}
+
+	var unit time.Duration
+	switch matches[reInterval.SubexpIndex("unit")] {
+	case "s":
+		unit = time.Second
+	case "m":
+		unit = time.Minute
+	case "h":
+		unit = time.Hour
+	case "d":
+		unit = 24 * time.Hour
+	case "w":
+		unit = 7 * 24 * time.Hour
+	}
+
+	return value * unit.Milliseconds(), nil
+}
+
+// metricSeriesKey builds the unique key of a log metric series from the numeric field name and
+// the current values of its label fields. The labels are rendered by name so that two different
+// label sets can never share the same key, whatever the characters used in the label values.
+//
+// Parameters:
+//   - numericFieldKey: The name of the numeric field in the log search results.
+//   - sLabelFields: The label fields of the log metric with their current values.
+//
+// Returns:
+//   - The unique key of the series, e.g. count{level=ERROR, source=app}.
+func metricSeriesKey(numericFieldKey string, sLabelFields []*models.LabelFieldMetadata) string {
+	labels := make(data.Labels, len(sLabelFields))
+	for _, labelFieldMetadata := range sLabelFields {
+		labels[labelFieldMetadata.LabelName] = labelFieldMetadata.LabelValue
+	}
+
+	return numericFieldKey + "{" + labels.String() + "}"
+}
+
+// orderedFieldKeys returns the keys of the data field definitions in a deterministic order:
+// the time fields first, then all other fields sorted by key. Grafana server side expressions
+// and alerting expect the same field order for every evaluation of a query.
 //
 // Parameters:
-//   - slice: A slice of strings where duplicates will be removed.
+//   - mFieldData: The data field definitions produced by a query.
 //
 // Returns:
-//   - A new slice containing only the unique strings from the input slice,
-//     in the order they first appeared.
-func uniqueStrings(slice []string) []string {
-	// Create a map to keep track of seen strings.
-	seen := make(map[string]struct{})
-
-	// Create a new list to store unique strings.
-	unique := []string{}
-
-	// Iterate over the slice.
-	for _, str := range slice {
-		// Check if the string has been seen before.
-		if _, ok := seen[str]; !ok {
-			// Add the string to the map.
-			seen[str] = struct{}{}
-
-			// Add the string to the unique list.
-			unique = append(unique, str)
+//   - The sorted keys of the data field definitions.
+func orderedFieldKeys(mFieldData map[string]*DataFieldElements) []string {
+	keys := make([]string, 0, len(mFieldData))
+	for key := range mFieldData {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		iTime := mFieldData[keys[i]].Type == FieldValueType(constants.ValueType_Time)
+		jTime := mFieldData[keys[j]].Type == FieldValueType(constants.ValueType_Time)
+		if iTime != jTime {
+			return iTime
+		}
+		return keys[i] < keys[j]
+	})
+
+	return keys
+}
+
+// isNumericFrame reports whether the data field definitions describe a wide time series, i.e.
+// one time field and only numeric fields, which can be reduced by Grafana server side expressions.
+//
+// Parameters:
+//   - mFieldData: The data field definitions produced by a query.
+//
+// Returns:
+//   - true if the fields form a wide time series frame, false otherwise.
+func isNumericFrame(mFieldData map[string]*DataFieldElements) bool {
+	timeFields := 0
+	numericFields := 0
+	for _, fieldDataElems := range mFieldData {
+		switch fieldDataElems.Type {
+		case FieldValueType(constants.ValueType_Time):
+			timeFields++
+		case FieldValueType(constants.ValueType_Float64), FieldValueType(constants.ValueType_Int):
+			numericFields++
+		default:
+			return false
 		}
 	}
 
-	// Return the list of unique strings.
-	return unique
+	return timeFields == 1 && numericFields > 0
 }
 
-// extractField extracts the value of a specified field from a JSON string.
-// It unmarshals the JSON string into a map and returns the value of the specified field.
+// lookupLogField returns the value found at a dotted path within a log record content, e.g.
+// "data.message" or "oracle.logid".
 //
 // Parameters:
-//   - jsonStr: A string containing the JSON data.
-//   - field: The name of the field whose value should be extracted from the JSON.
+//   - logContent: The logContent element of a log search result.
+//   - path: The dotted path of the field.
 //
 // Returns:
-//   - A string containing the value of the specified field, or an error if the field is not found
-//     or if there is an issue with unmarshaling the JSON.
-//
-// Errors:
-//   - If the JSON string cannot be unmarshaled, or if the field is not found in the JSON, an error is returned.
-func extractField(jsonStr string, field string) (string, error) {
-	// Unmarshal the JSON string into a map.
-	var data map[string]interface{}
-	field = strings.Trim(field, "\\\"")
-	err := json.Unmarshal([]byte(jsonStr), &data)
-	if err != nil {
-		return "", fmt.Errorf("error unmarshaling JSON: %v", err)
-	}
-
-	// Check if the field exists in the map.
-	value, ok := data[field]
-	if !ok {
-		return "", errors.New("field not found in JSON " + field)
+//   - The value of the field.
+//   - false if any element of the path does not exist.
+func lookupLogField(logContent map[string]interface{}, path string) (interface{}, bool) {
+	var value interface{} = logContent
+	for _, element := range strings.Split(path, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = m[element]; !ok {
+			return nil, false
+		}
+	}
+
+	return value, true
+}
+
+// logFieldString converts a log field value into a string. Strings are returned as is,
+// nil values as an empty string and any other value as JSON.
+//
+// Parameters:
+//   - value: The log field value.
+//
+// Returns:
+//   - The string representation of the value.
+func logFieldString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		logJSON, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(logJSON)
+	}
+}
+
+// parseLogTime converts a log field value into a time. Both RFC3339 strings and epoch
+// milliseconds, as returned by the OCI Logging service, are supported.
+//
+// Parameters:
+//   - value: The log field value.
+//
+// Returns:
+//   - The time represented by the value.
+//   - An error if the value is not a supported time representation.
+func parseLogTime(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case string:
+		return time.Parse(time.RFC3339Nano, v)
+	case float64:
+		return time.UnixMilli(int64(v)).UTC(), nil
+	default:
+		return time.Time{}, fmt.Errorf("unsupported time value: %v", value)
+	}
+}
+
+// Pipeline commands of a log records query before which an injected sort command is inserted, so that
+// they apply to the sorted records and the time of the records is not projected out before the sort
+var reSortInsertionCommand = regexp.MustCompile(`^(?i)(head|tail|limit|fields|eval)\b`)
+
+// Sort command of a log records query
+var reSortCommand = regexp.MustCompile(`^(?i)sort\b`)
+
+// withSortClause adds a sort command on the time of the log records to a log records query that does not
+// sort its records, so that the records kept when the results exceed the row cap are the most recent ones,
+// or the oldest ones for an ascending sort. The sort command is inserted before the commands limiting the
+// number of records, e.g. head, or projecting their fields, i.e. fields and eval, and appended otherwise.
+// Queries with a sort command are not changed.
+//
+// Parameters:
+//   - searchQuery: The log records query.
+//   - direction: The sort direction, "asc" or "desc", the default direction when empty.
+//
+// Returns:
+//   - The query with a sort command.
+func withSortClause(searchQuery string, direction string) string {
+	if direction != constants.SortDirection_Asc && direction != constants.SortDirection_Desc {
+		direction = constants.DefaultSortDirection
+	}
+
+	commands := splitQueryPipeline(searchQuery)
+	insertAt := len(commands)
+	for i, command := range commands {
+		if reSortCommand.MatchString(command) {
+			return searchQuery
+		}
+		if i > 0 && insertAt == len(commands) && reSortInsertionCommand.MatchString(command) {
+			insertAt = i
+		}
+	}
+
+	sorted := make([]string, 0, len(commands)+1)
+	sorted = append(sorted, commands[:insertAt]...)
+	sorted = append(sorted, "sort by datetime "+direction)
+	sorted = append(sorted, commands[insertAt:]...)
+
+	return strings.Join(sorted, " | ")
+}
+
+// splitQueryPipeline splits a logging search query into the commands of its pipeline, e.g.
+// `search "ocid" | where level = 'ERROR' | sort by datetime desc` gives three commands.
+// Pipe characters within quoted strings are not treated as separators.
+//
+// Parameters:
+//   - searchQuery: The logging search query.
+//
+// Returns:
+//   - The trimmed commands of the pipeline.
+func splitQueryPipeline(searchQuery string) []string {
+	commands := make([]string, 0)
+	var quote rune
+	var current strings.Builder
+
+	for _, c := range searchQuery {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '|':
+			commands = append(commands, strings.TrimSpace(current.String()))
+			current.Reset()
+			continue
+		}
+		current.WriteRune(c)
 	}
+	commands = append(commands, strings.TrimSpace(current.String()))
 
-	// Convert the value to a string and return it.
-	return fmt.Sprintf("%v", value), nil
+	return commands
 }
@@ -0,0 +1,94 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/oracle/oci-grafana-logs/pkg/plugin/constants"
+)
+
+func TestSplitQueryPipeline(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: `search "ocid1.compartment.oc1..a"`, want: []string{`search "ocid1.compartment.oc1..a"`}},
+		{
+			query: `search "ocid1.compartment.oc1..a" | where level = 'ERROR' |sort by datetime desc`,
+			want:  []string{`search "ocid1.compartment.oc1..a"`, `where level = 'ERROR'`, `sort by datetime desc`},
+		},
+		{
+			query: `search "a|b" | where data.message = 'x | y' | where data.path = "/a|b"`,
+			want:  []string{`search "a|b"`, `where data.message = 'x | y'`, `where data.path = "/a|b"`},
+		},
+		{query: `search "a" | where data.message = "it's | fine"`, want: []string{`search "a"`, `where data.message = "it's | fine"`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := splitQueryPipeline(tt.query); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("splitQueryPipeline() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithSortClause(t *testing.T) {
+	tests := []struct {
+		query     string
+		direction string
+		want      string
+	}{
+		{query: `search "a"`, want: `search "a" | sort by datetime desc`},
+		{query: `search "a"`, direction: constants.SortDirection_Asc, want: `search "a" | sort by datetime asc`},
+		{query: `search "a"`, direction: "sideways", want: `search "a" | sort by datetime desc`},
+		{query: `search "a" | where level = 'ERROR'`, want: `search "a" | where level = 'ERROR' | sort by datetime desc`},
+		{query: `search "a" | head 10`, want: `search "a" | sort by datetime desc | head 10`},
+		{query: `search "a" | where level = 'x' | LIMIT 5 | tail 2`, want: `search "a" | where level = 'x' | sort by datetime desc | LIMIT 5 | tail 2`},
+		{
+			query: `search "a" | eval size = data.bytes / 1024 | fields data.message, size`,
+			want:  `search "a" | sort by datetime desc | eval size = data.bytes / 1024 | fields data.message, size`,
+		},
+		{query: `search "a" | fields data.message`, want: `search "a" | sort by datetime desc | fields data.message`},
+		{query: `search "a" | sort by data.level`, want: `search "a" | sort by data.level`},
+		{query: `search "a" | where data.message = 'head | sort'`, want: `search "a" | where data.message = 'head | sort' | sort by datetime desc`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query+" "+tt.direction, func(t *testing.T) {
+			if got := withSortClause(tt.query, tt.direction); got != tt.want {
+				t.Fatalf("withSortClause() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// The chunking decision is made on the query with its sort command: a query without sort command is
+// searched in chunks in the order of the sort direction.
+func TestWithSortClauseChunking(t *testing.T) {
+	tests := []struct {
+		query          string
+		direction      string
+		wantChunkable  bool
+		wantDescending bool
+	}{
+		{query: `search "a"`, wantChunkable: true, wantDescending: true},
+		{query: `search "a"`, direction: constants.SortDirection_Asc, wantChunkable: true},
+		{query: `search "a" | fields data.message`, wantChunkable: true, wantDescending: true},
+		{query: `search "a" | head 10`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query+" "+tt.direction, func(t *testing.T) {
+			chunkable, descending := chunkableQuery(withSortClause(tt.query, tt.direction))
+			if chunkable != tt.wantChunkable || descending != tt.wantDescending {
+				t.Fatalf("chunkableQuery(withSortClause()) = %v, %v, want %v, %v", chunkable, descending, tt.wantChunkable, tt.wantDescending)
+			}
+		})
+	}
+}
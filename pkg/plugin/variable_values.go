@@ -0,0 +1,116 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/oracle/oci-grafana-logs/pkg/plugin/constants"
+	"github.com/oracle/oci-grafana-logs/pkg/plugin/models"
+)
+
+// variableFieldPaths splits the field of a template variable query into the paths of the text and of the value
+// of the variable. The field is either a single path, used for both, or "<text path>, <value path>", e.g.
+// "data.logName, oracle.logid".
+//
+// Parameters:
+//   - field: The field of the template variable query.
+//
+// Returns:
+//   - string: The path of the text of the variable values.
+//   - string: The path of the value of the variable values.
+func variableFieldPaths(field string) (string, string) {
+	textPath, valuePath, found := strings.Cut(field, ",")
+	textPath = strings.Trim(strings.TrimSpace(textPath), "\\\"'")
+	if !found {
+		return textPath, textPath
+	}
+	return textPath, strings.Trim(strings.TrimSpace(valuePath), "\\\"'")
+}
+
+// logContentVariableField returns the value of a field of a log record for a template variable. The path is
+// first looked up within the data section of the log record, so that "eventName" is "data.eventName", then
+// from the root of the log record, e.g. "oracle.logid" or "data.identity.principalName".
+//
+// Parameters:
+//   - logContent: The logContent element of a log search result.
+//   - path: The path of the field.
+//
+// Returns:
+//   - string: The value of the field.
+//   - bool: false if the field does not exist.
+func logContentVariableField(logContent map[string]interface{}, path string) (string, bool) {
+	if path == "" {
+		return "", false
+	}
+	value, ok := lookupLogField(logContent, constants.LogSearchResultsField_Data+"."+path)
+	if !ok {
+		value, ok = lookupLogField(logContent, path)
+	}
+	if !ok || value == nil {
+		return "", false
+	}
+	return logFieldString(value), true
+}
+
+// aggregatedVariableField returns the value of a field of an aggregated search result, e.g. a row of
+// "summarize count() by data.eventName", for a template variable. The fields of aggregated results are named
+// after their expression, e.g. "data.eventName", rather than nested. Without path, the first field that is
+// not the time or the count is returned.
+//
+// Parameters:
+//   - row: The data of the search result.
+//   - path: The path of the field, empty for the first grouped by field.
+//
+// Returns:
+//   - string: The value of the field.
+//   - bool: false if the field does not exist.
+func aggregatedVariableField(row map[string]interface{}, path string) (string, bool) {
+	if path == "" {
+		value, err := FilterMap(row)
+		return value, err == nil
+	}
+	value, ok := row[path]
+	if !ok {
+		value, ok = lookupLogField(row, path)
+	}
+	if !ok || value == nil {
+		return "", false
+	}
+	return logFieldString(value), true
+}
+
+// filterVariableValues sorts the values of a template variable by text, removes the duplicates and keeps
+// the values whose text matches the regex, as the regex of a Grafana template variable does.
+//
+// Parameters:
+//   - values: The values of the template variable.
+//   - filter: The regex of the values to keep, nil to keep all values.
+//
+// Returns:
+//   - []models.VariableValue: The sorted, unique and filtered values.
+func filterVariableValues(values []models.VariableValue, filter *regexp.Regexp) []models.VariableValue {
+	sort.SliceStable(values, func(i, j int) bool {
+		if values[i].Text != values[j].Text {
+			return values[i].Text < values[j].Text
+		}
+		return values[i].Value < values[j].Value
+	})
+
+	filtered := make([]models.VariableValue, 0, len(values))
+	for i, value := range values {
+		if i > 0 && value == values[i-1] {
+			continue
+		}
+		if filter != nil && !filter.MatchString(value.Text) {
+			continue
+		}
+		filtered = append(filtered, value)
+	}
+	return filtered
+}
@@ -0,0 +1,117 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+
+	"github.com/oracle/oci-grafana-logs/pkg/plugin/models"
+)
+
+func TestVariableFieldPaths(t *testing.T) {
+	tests := []struct {
+		field     string
+		wantText  string
+		wantValue string
+	}{
+		{field: "eventName", wantText: "eventName", wantValue: "eventName"},
+		{field: ` "identity.principalName" `, wantText: "identity.principalName", wantValue: "identity.principalName"},
+		{field: "data.logName, oracle.logid", wantText: "data.logName", wantValue: "oracle.logid"},
+		{field: `'data.logName' ,\"oracle.logid\"`, wantText: "data.logName", wantValue: "oracle.logid"},
+		{field: "", wantText: "", wantValue: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			text, value := variableFieldPaths(tt.field)
+			if text != tt.wantText || value != tt.wantValue {
+				t.Fatalf("variableFieldPaths() = %q, %q, want %q, %q", text, value, tt.wantText, tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestFilterVariableValues(t *testing.T) {
+	values := []models.VariableValue{
+		{Text: "prod-b", Value: "ocid2"},
+		{Text: "dev", Value: "ocid3"},
+		{Text: "prod-a", Value: "ocid1"},
+		{Text: "prod-b", Value: "ocid2"},
+		{Text: "prod-b", Value: "ocid0"},
+	}
+	tests := []struct {
+		name   string
+		filter *regexp.Regexp
+		want   []models.VariableValue
+	}{
+		{
+			name: "sorted by text then value, without duplicates",
+			want: []models.VariableValue{
+				{Text: "dev", Value: "ocid3"},
+				{Text: "prod-a", Value: "ocid1"},
+				{Text: "prod-b", Value: "ocid0"},
+				{Text: "prod-b", Value: "ocid2"},
+			},
+		},
+		{
+			name:   "filtered on the text",
+			filter: regexp.MustCompile("^prod-"),
+			want: []models.VariableValue{
+				{Text: "prod-a", Value: "ocid1"},
+				{Text: "prod-b", Value: "ocid0"},
+				{Text: "prod-b", Value: "ocid2"},
+			},
+		},
+		{name: "nothing matches", filter: regexp.MustCompile("^ocid"), want: []models.VariableValue{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := filterVariableValues(append([]models.VariableValue{}, values...), tt.filter)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("filterVariableValues() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got := filterVariableValues(nil, nil); got == nil || len(got) != 0 {
+		t.Fatalf("filterVariableValues(nil) = %#v, want an empty list", got)
+	}
+}
+
+func TestGetLogsInvalidTenancy(t *testing.T) {
+	tests := []struct {
+		name        string
+		tenancyMode string
+		tenancy     string
+	}{
+		{name: "unknown tenancy in multitenancy mode", tenancyMode: "multitenancy", tenancy: "ocid1.tenancy.oc1..stale"},
+		{name: "no tenancy configured in single tenancy mode", tenancyMode: "single", tenancy: "DEFAULT/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &OCIDatasource{
+				logger:        log.DefaultLogger,
+				tenancyAccess: map[string]*logTenancyAccess{},
+				settings:      &models.OCIDatasourceSettings{TenancyMode: tt.tenancyMode},
+			}
+			values, err := o.getLogs(context.Background(), tt.tenancy, "search \"ocid1.compartment.oc1..a\"", "data.message", "", 0, 0)
+			if err == nil || !strings.Contains(err.Error(), "invalid tenancy") {
+				t.Fatalf("getLogs() error = %v, want an invalid tenancy error", err)
+			}
+			if values != nil {
+				t.Fatalf("getLogs() = %v, want no values", values)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+
+	"github.com/oracle/oci-grafana-logs/pkg/plugin/constants"
+	"github.com/oracle/oci-grafana-logs/pkg/plugin/models"
+)
+
+// newWorkloadPrincipalDatasource creates a datasource of the given environment whose OCI endpoints are
+// all overridden by endpoint.
+func newWorkloadPrincipalDatasource(t *testing.T, environment string, endpoint string) *OCIDatasource {
+	t.Helper()
+	settings, _ := json.Marshal(models.OCIDatasourceSettings{
+		Environment: environment,
+		TenancyMode: "single",
+		Endpoints:   models.EndpointSettings{LoggingSearch: endpoint, LoggingManagement: endpoint, Identity: endpoint},
+	})
+	instance, err := NewOCIDatasource(context.Background(), backend.DataSourceInstanceSettings{UID: t.Name(), JSONData: settings})
+	if err != nil {
+		t.Fatalf("NewOCIDatasource() error = %v", err)
+	}
+	o := instance.(*OCIDatasource)
+	t.Cleanup(o.Dispose)
+	if _, ok := o.tenancyAccess[SingleTenancyKey]; !ok || len(o.tenancyAccess) != 1 {
+		t.Fatalf("tenancies = %v, want the single tenancy only", o.tenancyAccess)
+	}
+	return o
+}
+
+func TestResourcePrincipalConnectivity(t *testing.T) {
+	const tenancy = "ocid1.tenancy.oc1..rp"
+	claims, _ := json.Marshal(map[string]interface{}{"res_tenant": tenancy, "exp": time.Now().Add(time.Hour).Unix()})
+	rpst := "eyJhbGciOiJSUzI1NiJ9." + base64.RawURLEncoding.EncodeToString(claims) + ".c2lnbmF0dXJl"
+
+	var mu sync.Mutex
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		requests = append(requests, req.Method+" "+req.URL.Path)
+		mu.Unlock()
+		// The requests are signed with the resource principal session token
+		if !strings.Contains(req.Header.Get("Authorization"), `keyId="ST$`+rpst+`"`) {
+			rw.WriteHeader(http.StatusUnauthorized)
+			_, _ = rw.Write([]byte(`{"code":"NotAuthenticated","message":"not signed with the session token"}`))
+			return
+		}
+		if req.Method == http.MethodPost {
+			_, _ = rw.Write([]byte(`{}`))
+			return
+		}
+		_, _ = rw.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	t.Setenv("OCI_RESOURCE_PRINCIPAL_VERSION", "2.2")
+	t.Setenv("OCI_RESOURCE_PRINCIPAL_RPST", rpst)
+	t.Setenv("OCI_RESOURCE_PRINCIPAL_PRIVATE_PEM", string(readTestKey(t, "pkcs1.pem")))
+	t.Setenv("OCI_RESOURCE_PRINCIPAL_REGION", "us-ashburn-1")
+
+	o := newWorkloadPrincipalDatasource(t, constants.Environment_ResourcePrincipal, server.URL)
+	if err := o.TestConnectivity(context.Background()); err != nil {
+		t.Fatalf("TestConnectivity() error = %v", err)
+	}
+
+	report, err := o.CheckTenanciesHealth(context.Background())
+	if err != nil {
+		t.Fatalf("CheckTenanciesHealth() error = %v", err)
+	}
+	health := report.Tenancies[0]
+	if !health.Healthy || health.TenancyOCID != tenancy || health.Region != "us-ashburn-1" || health.Auth != models.HealthCheckOK || !health.ListLogGroups {
+		t.Fatalf("CheckTenanciesHealth() = %+v, want the resource principal tenancy healthy", health)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, want := range []string{"GET /20160918/tenancies/" + tenancy + "/regionSubscriptions", "POST /20190909/search", "GET /20200531/logGroups"} {
+		found := false
+		for _, request := range requests {
+			found = found || request == want
+		}
+		if !found {
+			t.Errorf("requests = %v, want %s", requests, want)
+		}
+	}
+}
+
+// The OKE workload identity provider exchanges the service account token of the pod, read from a fixed
+// path, for a session token on the proxymux port of the Kubernetes service host, and the OCI SDK makes the
+// exchange when the clients are built: outside of a cluster, only the failing exchange can be checked.
+func TestOkeWorkloadIdentityClients(t *testing.T) {
+	if _, err := os.Stat("/var/run/secrets/kubernetes.io/serviceaccount/token"); err == nil {
+		t.Skip("running in a Kubernetes pod, the session token exchange would not fail")
+	}
+	caPath := filepath.Join(t.TempDir(), "ca.crt")
+	if err := os.WriteFile(caPath, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("OCI_RESOURCE_PRINCIPAL_VERSION", "2.2")
+	t.Setenv("OCI_RESOURCE_PRINCIPAL_REGION", "eu-frankfurt-1")
+	t.Setenv("OCI_KUBERNETES_SERVICE_ACCOUNT_CERT_PATH", caPath)
+	t.Setenv("KUBERNETES_SERVICE_HOST", "127.0.0.1")
+
+	settings, _ := json.Marshal(models.OCIDatasourceSettings{Environment: constants.Environment_OkeWorkloadIdentity, TenancyMode: "single"})
+	_, err := NewOCIDatasource(context.Background(), backend.DataSourceInstanceSettings{UID: t.Name(), JSONData: settings})
+	if err == nil || !strings.Contains(err.Error(), "service account token") {
+		t.Fatalf("NewOCIDatasource() error = %v, want the failing exchange of the service account token", err)
+	}
+}
+
+func TestWorkloadPrincipalMissingEnvironment(t *testing.T) {
+	for _, environment := range []string{constants.Environment_ResourcePrincipal, constants.Environment_OkeWorkloadIdentity} {
+		t.Run(environment, func(t *testing.T) {
+			t.Setenv("OCI_RESOURCE_PRINCIPAL_VERSION", "")
+			os.Unsetenv("OCI_RESOURCE_PRINCIPAL_VERSION")
+			settings, _ := json.Marshal(models.OCIDatasourceSettings{Environment: environment, TenancyMode: "single"})
+			if _, err := NewOCIDatasource(context.Background(), backend.DataSourceInstanceSettings{UID: t.Name(), JSONData: settings}); err == nil {
+				t.Fatalf("NewOCIDatasource() error = nil, want an error outside of a %s", environment)
+			}
+		})
+	}
+}
+
+func TestLoadSettingsEnvironment(t *testing.T) {
+	tests := []struct {
+		environment    string
+		configProfile  string
+		configFilePath string
+	}{
+		{environment: constants.Environment_Local, configProfile: constants.DEFAULT_INSTANCE_PROFILE},
+		{environment: constants.Environment_Instance, configProfile: constants.DEFAULT_INSTANCE_PROFILE},
+		{environment: constants.Environment_ResourcePrincipal, configProfile: constants.DEFAULT_INSTANCE_PROFILE},
+		{environment: constants.Environment_OkeWorkloadIdentity, configProfile: constants.DEFAULT_INSTANCE_PROFILE},
+		{environment: constants.Environment_SessionToken, configProfile: constants.DefaultOCIConfigProfile, configFilePath: constants.DefaultOCIConfigFilePath},
+		{environment: constants.Environment_ConfigFile, configProfile: constants.DEFAULT_INSTANCE_PROFILE, configFilePath: constants.DefaultOCIConfigFilePath},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.environment, func(t *testing.T) {
+			settings, _ := json.Marshal(map[string]string{"environment": tt.environment, "tenancymode": "single"})
+			d := &models.OCIDatasourceSettings{}
+			if err := d.Load(backend.DataSourceInstanceSettings{JSONData: settings}); err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if d.Environment != tt.environment || d.ConfigProfile != tt.configProfile || d.ConfigFilePath != tt.configFilePath {
+				t.Fatalf("Load() = environment %q, profile %q, config file %q, want %q, %q, %q",
+					d.Environment, d.ConfigProfile, d.ConfigFilePath, tt.environment, tt.configProfile, tt.configFilePath)
+			}
+		})
+	}
+}
@@ -4,13 +4,13 @@
 */
 
 import React, {KeyboardEvent, useEffect, useState } from 'react';
-import { InlineField, InlineFieldRow, FieldSet, SegmentAsync, Input, TextArea } from '@grafana/ui';
+import { InlineField, InlineFieldRow, FieldSet, SegmentAsync, Input, TextArea, Select } from '@grafana/ui';
 import { QueryEditorProps, SelectableValue } from '@grafana/data';
 import { getTemplateSrv } from '@grafana/runtime';
 import { OCIDataSource } from './datasource';
-import { OCIDataSourceOptions, OCIQuery, QueryPlaceholder } from './types';
+import { OCIDataSourceOptions, OCIQuery, QueryPlaceholder, ANNOTATION_QUERY_TYPE } from './types';
 //import QueryModel from './query_model';
-import { TenancyChoices } from './config.options';
+import { TenancyChoices, FillModeOptions, SortDirectionOptions } from './config.options';
 
 type Props = QueryEditorProps<OCIDataSource, OCIQuery, OCIDataSourceOptions>;
 
@@ -273,6 +273,100 @@ export const QueryEditor: React.FC<Props> = (props) => {
                 })}
                 />
       </InlineField>
+        {query.queryType === ANNOTATION_QUERY_TYPE && (
+          <>
+            <InlineFieldRow>
+              <InlineField label="TIME FIELD" labelWidth={20} tooltip="Log field holding the annotation time">
+                <Input
+                  className="width-14"
+                  placeholder="time"
+                  defaultValue={query.annotation?.timeField}
+                  onBlur={(e) => onChange({ ...query, annotation: { ...query.annotation, timeField: e.currentTarget.value } })}
+                />
+              </InlineField>
+              <InlineField label="TIME END FIELD" labelWidth={20} tooltip="Optional log field holding the annotation end time">
+                <Input
+                  className="width-14"
+                  defaultValue={query.annotation?.timeEndField}
+                  onBlur={(e) => onChange({ ...query, annotation: { ...query.annotation, timeEndField: e.currentTarget.value } })}
+                />
+              </InlineField>
+            </InlineFieldRow>
+            <InlineFieldRow>
+              <InlineField label="TITLE FIELD" labelWidth={20} tooltip="Optional log field holding the annotation title">
+                <Input
+                  className="width-14"
+                  defaultValue={query.annotation?.titleField}
+                  onBlur={(e) => onChange({ ...query, annotation: { ...query.annotation, titleField: e.currentTarget.value } })}
+                />
+              </InlineField>
+              <InlineField label="TEXT FIELD" labelWidth={20} tooltip="Log field holding the annotation text">
+                <Input
+                  className="width-14"
+                  placeholder="data.message"
+                  defaultValue={query.annotation?.textField}
+                  onBlur={(e) => onChange({ ...query, annotation: { ...query.annotation, textField: e.currentTarget.value } })}
+                />
+              </InlineField>
+            </InlineFieldRow>
+            <InlineFieldRow>
+              <InlineField label="TAGS FIELDS" labelWidth={20} tooltip="Optional comma separated list of log fields used as tags">
+                <Input
+                  className="width-14"
+                  defaultValue={query.annotation?.tagsField}
+                  onBlur={(e) => onChange({ ...query, annotation: { ...query.annotation, tagsField: e.currentTarget.value } })}
+                />
+              </InlineField>
+              <InlineField label="LIMIT" labelWidth={20} tooltip="Maximum number of annotations, up to 1000">
+                <Input
+                  className="width-14"
+                  type="number"
+                  placeholder="100"
+                  defaultValue={query.annotation?.limit}
+                  onBlur={(e) => onChange({ ...query, annotation: { ...query.annotation, limit: parseInt(e.currentTarget.value, 10) || undefined } })}
+                />
+              </InlineField>
+            </InlineFieldRow>
+          </>
+        )}
+        <InlineFieldRow>
+          <InlineField label="FILL" labelWidth={20} tooltip="How empty rounddown buckets of time series queries are filled">
+            <Select
+              className="width-14"
+              options={FillModeOptions}
+              value={query.fillMode ?? ''}
+              onChange={(data) => {
+                onChange({ ...query, fillMode: data.value });
+                onRunQuery();
+              }}
+            />
+          </InlineField>
+          <InlineField label="SORT" labelWidth={20} tooltip="Time order of the log records when the query has no sort command">
+            <Select
+              className="width-14"
+              options={SortDirectionOptions}
+              value={query.sortDirection ?? 'desc'}
+              onChange={(data) => {
+                onChange({ ...query, sortDirection: data.value });
+                onRunQuery();
+              }}
+            />
+          </InlineField>
+        </InlineFieldRow>
+        <InlineFieldRow>
+          <InlineField label="TIMEOUT" labelWidth={20} tooltip="Optional timeout of the query in seconds, overrides the query timeout of the datasource">
+            <Input
+              className="width-14"
+              type="number"
+              placeholder="datasource"
+              defaultValue={query.timeout}
+              onBlur={(e) => {
+                onChange({ ...query, timeout: parseInt(e.currentTarget.value, 10) || undefined });
+                onRunQuery();
+              }}
+            />
+          </InlineField>
+        </InlineFieldRow>
       </FieldSet>
     </>
   );
@@ -20,10 +20,18 @@ import { SelectableValue } from '@grafana/data';
  *
  * @property {string} OCI_USER - Represents the 'local' authentication method, where OCI user credentials are used.
  * @property {string} OCI_INSTANCE - Represents the 'OCI Instance' authentication method, where the Grafana instance is running within an OCI environment and uses instance principals.
+ * @property {string} OCI_RESOURCE_PRINCIPAL - Represents the 'OCI Resource Principal' authentication method, used when Grafana runs in OCI Functions or Container Instances.
+ * @property {string} OKE_WORKLOAD_IDENTITY - Represents the 'OKE Workload Identity' authentication method, used when Grafana runs in an OKE pod.
+ * @property {string} OCI_SESSION_TOKEN - Represents the 'OCI Session Token' authentication method, where the session of an OCI CLI config file profile is used.
+ * @property {string} OCI_CONFIG_FILE - Represents the 'OCI Config File' authentication method, where user principal profiles are read from an OCI config file on disk.
 */
 export enum AuthProviders {
   OCI_USER = 'local',
   OCI_INSTANCE = 'OCI Instance',
+  OCI_RESOURCE_PRINCIPAL = 'OCI Resource Principal',
+  OKE_WORKLOAD_IDENTITY = 'OKE Workload Identity',
+  OCI_SESSION_TOKEN = 'OCI Session Token',
+  OCI_CONFIG_FILE = 'OCI Config File',
 }
 
 /**
@@ -48,7 +56,7 @@ export const namespaces = ['oci_computeagent', 'oci_blockstore', 'oci_lbaas', 'o
  * // Example usage:
  * // const myEnvironment = environments[1]; // 'OCI Instance'
 */
-export const environments = ['local', 'OCI Instance'];
+export const environments = ['local', 'OCI Instance', 'OCI Resource Principal', 'OKE Workload Identity', 'OCI Session Token', 'OCI Config File'];
 
 /**
  * @enum TenancyChoices
@@ -105,4 +113,80 @@ export const AuthProviderOptions = [
     value: AuthProviders.OCI_INSTANCE,
     description: 'The grafana instance is configured in OCI environment',
   },
+  {
+    label: 'OCI Resource Principal',
+    value: AuthProviders.OCI_RESOURCE_PRINCIPAL,
+    description: 'The grafana instance runs in OCI Functions or Container Instances',
+  },
+  {
+    label: 'OKE Workload Identity',
+    value: AuthProviders.OKE_WORKLOAD_IDENTITY,
+    description: 'The grafana instance runs in an OKE pod with workload identity',
+  },
+  {
+    label: 'OCI Session Token',
+    value: AuthProviders.OCI_SESSION_TOKEN,
+    description: 'The grafana instance uses the session of an OCI CLI config file profile',
+  },
+  {
+    label: 'OCI Config File',
+    value: AuthProviders.OCI_CONFIG_FILE,
+    description: 'The grafana instance reads oci user principals from an OCI config file on disk',
+  },
+] as Array<SelectableValue<string>>;
+
+/**
+ * @constant FillModeOptions
+ * @description
+ * An array of selectable value options for filling the empty rounddown buckets of time series queries.
+ *
+ * @type {SelectableValue<string>[]}
+ * @example
+ * // Example usage:
+ * // <Select options={FillModeOptions} />
+*/
+export const FillModeOptions = [
+  {
+    label: 'None',
+    value: '',
+    description: 'Only buckets returned by OCI Logging are shown',
+  },
+  {
+    label: 'Zero',
+    value: 'zero',
+    description: 'Empty buckets are filled with zero',
+  },
+  {
+    label: 'Null',
+    value: 'null',
+    description: 'Empty buckets are shown as gaps',
+  },
+  {
+    label: 'Previous',
+    value: 'previous',
+    description: 'Empty buckets repeat the previous value',
+  },
+] as Array<SelectableValue<string>>;
+
+/**
+ * @constant SortDirectionOptions
+ * @description
+ * An array of selectable value options for the time order of the log records of queries without a sort command.
+ *
+ * @type {SelectableValue<string>[]}
+ * @example
+ * // Example usage:
+ * // <Select options={SortDirectionOptions} />
+*/
+export const SortDirectionOptions = [
+  {
+    label: 'Newest first',
+    value: 'desc',
+    description: 'The most recent log records are returned when the results exceed the row limit',
+  },
+  {
+    label: 'Oldest first',
+    value: 'asc',
+    description: 'The oldest log records are returned when the results exceed the row limit',
+  },
 ] as Array<SelectableValue<string>>;
@@ -4,10 +4,30 @@
 */
 
 import _,{ isString} from 'lodash';
-import { DataSourceInstanceSettings, ScopedVars, MetricFindValue } from '@grafana/data';
-import { DataSourceWithBackend, getTemplateSrv } from '@grafana/runtime';
+import {
+  DataQueryRequest,
+  DataQueryResponse,
+  DataSourceInstanceSettings,
+  DataSourceWithLogsContextSupport,
+  DataSourceWithSupplementaryQueriesSupport,
+  LogRowContextOptions,
+  LogRowContextQueryDirection,
+  LogRowModel,
+  createDataFrame,
+  FieldType,
+  LiveChannelScope,
+  ScopedVars,
+  MetricFindValue,
+  queryLogsVolume,
+  SupplementaryQueryOptions,
+  SupplementaryQueryType,
+} from '@grafana/data';
+import { DataSourceWithBackend, getGrafanaLiveSrv, getTemplateSrv } from '@grafana/runtime';
+import { from, merge, Observable } from 'rxjs';
+import { mergeMap } from 'rxjs/operators';
 import {
   OCIResourceItem,
+  OCIVariableValueItem,
   ResponseParser,
   //OCIResourceMetadataItem,
 } from './resource.response.parser';
@@ -18,8 +38,12 @@ import {
   QueryPlaceholder,
   regionsQueryRegex,
   tenanciesQueryRegex,
+  compartmentsQueryRegex,
+  logGroupsQueryRegex,
+  logsQueryRegex,
   generalQueryRegex,
-  DEFAULT_TENANCY
+  DEFAULT_TENANCY,
+  ANNOTATION_QUERY_TYPE
 } from "./types";
 //import QueryModel from './query_model';
 
@@ -30,7 +54,9 @@ import {
  * 
  * @extends DataSourceWithBackend<OCIQuery, OCIDataSourceOptions>
 */
-export class OCIDataSource extends DataSourceWithBackend<OCIQuery, OCIDataSourceOptions> {
+export class OCIDataSource
+  extends DataSourceWithBackend<OCIQuery, OCIDataSourceOptions>
+  implements DataSourceWithSupplementaryQueriesSupport<OCIQuery>, DataSourceWithLogsContextSupport<OCIQuery> {
   private jsonData: any;
 
   /**
@@ -41,6 +67,154 @@ export class OCIDataSource extends DataSourceWithBackend<OCIQuery, OCIDataSource
   constructor(instanceSettings: DataSourceInstanceSettings<OCIDataSourceOptions>) {
     super(instanceSettings);
     this.jsonData = instanceSettings.jsonData;
+    this.annotations = {
+      prepareAnnotation: (json: any) => {
+        json.target = { ...json.target, queryType: ANNOTATION_QUERY_TYPE };
+        return json;
+      },
+      prepareQuery: (anno: any) => {
+        if (!anno.target) {
+          return undefined;
+        }
+        return { ...anno.target, refId: anno.name, queryType: ANNOTATION_QUERY_TYPE };
+      },
+    };
+  }
+
+  /**
+   * Runs the queries through the backend, or subscribes to the live tail channel of each
+   * query when Explore is in live streaming mode.
+   *
+   * @param {DataQueryRequest<OCIQuery>} request - The query request.
+   * @returns {Observable<DataQueryResponse>} The query response stream.
+   */
+  query(request: DataQueryRequest<OCIQuery>): Observable<DataQueryResponse> {
+    if (!request.liveStreaming) {
+      return super.query(request);
+    }
+
+    const streams = request.targets
+      .filter((target) => this.filterQuery(target))
+      .map((target) => {
+        const query = this.applyTemplateVariables({ ...target }, request.scopedVars);
+        return from(this.liveTailChannelPath(query)).pipe(
+          mergeMap((path) =>
+            getGrafanaLiveSrv().getDataStream({
+              key: `${request.requestId}-${query.refId}`,
+              addr: {
+                scope: LiveChannelScope.DataSource,
+                namespace: this.uid,
+                path: path,
+                data: { ...query },
+              },
+            })
+          )
+        );
+      });
+    return merge(...streams);
+  }
+
+  /**
+   * Returns the supplementary query types supported by the data source, used by Explore.
+   *
+   * @returns {SupplementaryQueryType[]} The log volume supplementary query type.
+   */
+  getSupportedSupplementaryQueryTypes(): SupplementaryQueryType[] {
+    return [SupplementaryQueryType.LogsVolume];
+  }
+
+  /**
+   * Builds the log volume query of a log records query. The backend rewrites the query into
+   * a count of log records per time bucket, grouped by level when available.
+   *
+   * @param {SupplementaryQueryOptions} options - The supplementary query options.
+   * @param {OCIQuery} query - The original query.
+   * @returns {OCIQuery | undefined} The log volume query, or undefined for unsupported types.
+   */
+  getSupplementaryQuery(options: SupplementaryQueryOptions, query: OCIQuery): OCIQuery | undefined {
+    if (options.type !== SupplementaryQueryType.LogsVolume) {
+      return undefined;
+    }
+    return { ...query, refId: `log-volume-${query.refId}`, logVolume: true };
+  }
+
+  /**
+   * Returns the data provider of a supplementary query type for the given request.
+   *
+   * @param {SupplementaryQueryType} type - The supplementary query type.
+   * @param {DataQueryRequest<OCIQuery>} request - The original request.
+   * @returns {Observable<DataQueryResponse> | undefined} The log volume data, or undefined for unsupported types.
+   */
+  getDataProvider(type: SupplementaryQueryType, request: DataQueryRequest<OCIQuery>): Observable<DataQueryResponse> | undefined {
+    if (type !== SupplementaryQueryType.LogsVolume) {
+      return undefined;
+    }
+    const targets = request.targets
+      .filter((target) => this.filterQuery(target))
+      .map((target) => this.getSupplementaryQuery({ type }, target))
+      .filter((target): target is OCIQuery => target !== undefined);
+    if (targets.length === 0) {
+      return undefined;
+    }
+    return queryLogsVolume(this, { ...request, targets }, { targets: request.targets });
+  }
+
+  /**
+   * Retrieves the log records written to the same log before or after a log record, for the
+   * "show context" feature of the logs panel and Explore.
+   *
+   * @param {LogRowModel} row - The log row to show the context of.
+   * @param {LogRowContextOptions} [options] - The context direction and number of lines.
+   * @param {OCIQuery} [query] - The query which returned the log row.
+   * @returns {Promise<DataQueryResponse>} The surrounding log records.
+   */
+  async getLogRowContext(row: LogRowModel, options?: LogRowContextOptions, query?: OCIQuery): Promise<DataQueryResponse> {
+    const fieldValue = (name: string) => row.dataFrame.fields.find((f) => f.name === name)?.values[row.rowIndex];
+    let oracle: any = {};
+    try {
+      oracle = JSON.parse(fieldValue('oracle') ?? '{}');
+    } catch (e) {
+      return { data: [] };
+    }
+    const reqBody: JSON = {
+      tenancy: query?.tenancy ?? DEFAULT_TENANCY,
+      logId: oracle.logid,
+      logGroupId: oracle.loggroupid,
+      compartmentId: oracle.compartmentid,
+      id: fieldValue('id'),
+      time: row.timeEpochMs,
+      limit: options?.limit,
+    } as unknown as JSON;
+    const response = await this.postResource(OCIResourceCall.LogContext, reqBody);
+    const backward = options?.direction !== LogRowContextQueryDirection.Forward;
+    // Backward context is shown newest first
+    const records: any[] = backward ? [...(response.before ?? [])].reverse() : response.after ?? [];
+    const frame = createDataFrame({
+      refId: row.dataFrame.refId,
+      fields: [
+        { name: 'timestamp', type: FieldType.time, values: records.map((r) => new Date(r.time).getTime()) },
+        { name: 'data', type: FieldType.string, values: records.map((r) => JSON.stringify(r.data)) },
+        { name: 'oracle', type: FieldType.string, values: records.map((r) => JSON.stringify(r.oracle)) },
+      ],
+    });
+    return { data: [frame] };
+  }
+
+  /**
+   * Retrieves the path of the live tail channel of a query from the backend, which derives it
+   * from the tenancy and the query, so that clients tailing the same query share the same
+   * channel, while different queries never do.
+   *
+   * @param {OCIQuery} query - The query to tail, with the template variables replaced.
+   * @returns {Promise<string>} The channel path.
+   */
+  async liveTailChannelPath(query: OCIQuery): Promise<string> {
+    const reqBody: JSON = {
+      tenancy: query.tenancy,
+      searchQuery: query.searchQuery,
+    } as unknown as JSON;
+    const response = await this.postResource(OCIResourceCall.LiveTail, reqBody);
+    return response.path;
   }
 
   /**
@@ -156,6 +330,46 @@ export class OCIDataSource extends DataSourceWithBackend<OCIQuery, OCIDataSource
       }
     }
 
+    const compartmentsQuery = query.match(compartmentsQueryRegex);
+    if (compartmentsQuery) {
+      const tenancy = this.jsonData.tenancymode === "multitenancy" ? templateSrv.replace(compartmentsQuery[1]) : DEFAULT_TENANCY;
+      const compartments = await this.getCompartments(tenancy);
+      return compartments.map(n => {
+        return { text: n.name, value: n.ocid };
+      });
+    }
+
+    const logGroupsQuery = query.match(logGroupsQueryRegex);
+    if (logGroupsQuery) {
+      let tenancy = DEFAULT_TENANCY;
+      let compartment = templateSrv.replace(logGroupsQuery[1]);
+      let region = templateSrv.replace(logGroupsQuery[2]);
+      if (this.jsonData.tenancymode === "multitenancy") {
+        tenancy = templateSrv.replace(logGroupsQuery[1]);
+        compartment = templateSrv.replace(logGroupsQuery[2]);
+        region = templateSrv.replace(logGroupsQuery[3]);
+      }
+      const logGroups = await this.getLogGroups(tenancy, compartment, region);
+      return logGroups.map(n => {
+        return { text: n.name, value: n.ocid };
+      });
+    }
+
+    const logsQuery = query.match(logsQueryRegex);
+    if (logsQuery) {
+      let tenancy = DEFAULT_TENANCY;
+      let logGroup = templateSrv.replace(logsQuery[1]);
+      let region = templateSrv.replace(logsQuery[2]);
+      if (this.jsonData.tenancymode === "multitenancy") {
+        tenancy = templateSrv.replace(logsQuery[1]);
+        logGroup = templateSrv.replace(logsQuery[2]);
+        region = templateSrv.replace(logsQuery[3]);
+      }
+      const logs = await this.getLogs(tenancy, logGroup, region);
+      return logs.map(n => {
+        return { text: n.name, value: n.ocid };
+      });
+    }
 
     const generalQuery = query.match(generalQueryRegex);
     if (generalQuery) {
@@ -164,19 +378,15 @@ export class OCIDataSource extends DataSourceWithBackend<OCIQuery, OCIDataSource
         const region = templateSrv.replace(generalQuery[2]);
         const putquery = templateSrv.replace(generalQuery[3]);
         const field = templateSrv.replace(generalQuery[4]);
-        const getquery = await this.getQuery(tenancy, region, putquery, field);
-        return getquery.map(n => {
-          return { text: n, value: n };
-        });        
+        const regex = templateSrv.replace(generalQuery[5]);
+        return await this.getQuery(tenancy, region, putquery, field, regex);
       } else {
         const tenancy = DEFAULT_TENANCY;
         const region = templateSrv.replace(generalQuery[1]);
         const putquery = templateSrv.replace(generalQuery[2]);
         const field = templateSrv.replace(generalQuery[3]);
-        const getquery = await this.getQuery(tenancy, region, putquery, field);
-        return getquery.map(n => {
-          return { text: n, value: n };
-        });      
+        const regex = templateSrv.replace(generalQuery[4]);
+        return await this.getQuery(tenancy, region, putquery, field, regex);
       }
     }
 
@@ -292,6 +502,103 @@ export class OCIDataSource extends DataSourceWithBackend<OCIQuery, OCIDataSource
     });
   }
 
+  /**
+   * Removes the quotes around a quoted argument of a variable query, e.g. "ocid1.compartment.oc1..xxx".
+   *
+   * @param {string} value - The argument, quoted or not.
+   * @returns {string} The argument without its quotes.
+  */
+  unquoteArgument(value: string): string {
+    return value ? value.replace(/^(["'])(.*)\1$/, '$2') : '';
+  }
+
+  /**
+   * Retrieves the compartments of a tenancy, the root compartment first then the others by path, e.g. "prod/network".
+   *
+   * @param tenancy - The tenancy identifier. If the tenancy is a variable, it will be interpolated.
+   * @returns A promise that resolves to the compartments, with their OCIDs.
+  */
+  async getCompartments(tenancy: string): Promise<OCIResourceItem[]> {
+    tenancy = this.unquoteArgument(getTemplateSrv().replace(tenancy));
+    if (tenancy === '') {
+      return [];
+    }
+    const reqBody: JSON = {
+      tenancy: tenancy,
+    } as unknown as JSON;
+    return this.postResource(OCIResourceCall.Compartments, reqBody).then((response) => {
+      return new ResponseParser().parseResources(response);
+    });
+  }
+
+  /**
+   * Retrieves the log groups of a compartment, sorted by name.
+   *
+   * @param tenancy - The tenancy identifier. If the tenancy is a variable, it will be interpolated.
+   * @param compartment - The OCID of the compartment, the log groups of the whole tenancy if empty.
+   * @param region - The region of the log groups, the region of the tenancy if empty.
+   * @returns A promise that resolves to the log groups, with their OCIDs.
+  */
+  async getLogGroups(tenancy: string, compartment = '', region = ''): Promise<OCIResourceItem[]> {
+    tenancy = this.unquoteArgument(getTemplateSrv().replace(tenancy));
+    compartment = this.unquoteArgument(getTemplateSrv().replace(compartment));
+    region = this.unquoteArgument(getTemplateSrv().replace(region));
+    if (tenancy === '') {
+      return [];
+    }
+    const reqBody: JSON = {
+      tenancy: tenancy,
+      compartment: compartment,
+      region: region,
+    } as unknown as JSON;
+    return this.postResource(OCIResourceCall.LogGroups, reqBody).then((response) => {
+      return new ResponseParser().parseResources(response);
+    });
+  }
+
+  /**
+   * Retrieves the logs of a log group, sorted by name.
+   *
+   * @param tenancy - The tenancy identifier. If the tenancy is a variable, it will be interpolated.
+   * @param logGroup - The OCID of the log group.
+   * @param region - The region of the log group, the region of the tenancy if empty.
+   * @returns A promise that resolves to the logs, with their OCIDs.
+  */
+  async getLogs(tenancy: string, logGroup: string, region = ''): Promise<OCIResourceItem[]> {
+    tenancy = this.unquoteArgument(getTemplateSrv().replace(tenancy));
+    logGroup = this.unquoteArgument(getTemplateSrv().replace(logGroup));
+    region = this.unquoteArgument(getTemplateSrv().replace(region));
+    if (tenancy === '' || logGroup === '') {
+      return [];
+    }
+    const reqBody: JSON = {
+      tenancy: tenancy,
+      logGroup: logGroup,
+      region: region,
+    } as unknown as JSON;
+    return this.postResource(OCIResourceCall.Logs, reqBody).then((response) => {
+      return new ResponseParser().parseResources(response);
+    });
+  }
+
+  /**
+   * Probes the OCI operations used by the plugin for a tenancy, and suggests the IAM policy
+   * statements granting the failing ones.
+   *
+   * @param {string} tenancy - The tenancy to probe.
+   * @param {string} compartment - The OCID of the compartment, the root compartment if empty.
+   * @param {string} logGroup - The OCID of the log group, optional.
+   * @returns {Promise<any>} The result of each operation and the suggested policy statements.
+   */
+  async runDiagnostics(tenancy: string, compartment = '', logGroup = ''): Promise<any> {
+    const reqBody: JSON = {
+      tenancy: tenancy,
+      compartment: compartment,
+      logGroup: logGroup,
+    } as unknown as JSON;
+    return this.postResource(OCIResourceCall.Diagnostics, reqBody);
+  }
+
   /**
    * Executes a query against OCI resources, resolving template variables where necessary.
    *
@@ -303,15 +610,17 @@ export class OCIDataSource extends DataSourceWithBackend<OCIQuery, OCIDataSource
    * @param {string} tenancy - The tenancy OCID or variable representing the tenancy.
    * @param {any} region - The OCI region where the query is executed.
    * @param {any} getquery - The specific query string to be executed.
-   * @param {any} field - The field or resource type to filter the query results.
-   * @returns {Promise<string[]>} - A promise resolving to an array of query results.
+   * @param {any} field - The field of the values, e.g. "oracle.logid", or "<text field>, <value field>".
+   * @param {any} regex - The regex of the texts of the values to keep, optional.
+   * @returns {Promise<OCIVariableValueItem[]>} - A promise resolving to the text and value pairs, sorted by text.
   */
   async getQuery(
     tenancy: string,
     region: any,
     getquery: any,
-    field: any
-  ): Promise<string[]>  {
+    field: any,
+    regex?: any
+  ): Promise<OCIVariableValueItem[]>  {
     if (this.isVariable(tenancy)) {
       let { tenancy: var_tenancy} = this.interpolateProps({tenancy});
       if (var_tenancy !== "") { 
@@ -355,6 +664,9 @@ export class OCIDataSource extends DataSourceWithBackend<OCIQuery, OCIDataSource
       field = '';
     }
 
+    // The regex is sent without its quotes, unlike the other arguments whose quotes are trimmed by the backend
+    regex = this.unquoteArgument(regex);
+
   // Check for special cases or undefined interval
     let timeStart = parseInt(getTemplateSrv().replace("${__from}"), 10);
     let timeEnd = parseInt(getTemplateSrv().replace("${__to}"), 10);
@@ -364,6 +676,7 @@ export class OCIDataSource extends DataSourceWithBackend<OCIQuery, OCIDataSource
       region: region,
       getquery: getquery,
       field: field,
+      regex: regex,
       timeStart: timeStart,
       timeEnd: timeEnd,
     } as unknown as JSON;
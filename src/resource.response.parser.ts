@@ -3,7 +3,7 @@
 ** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
 */
 
-import _ from 'lodash';
+import _, { isString } from 'lodash';
 
 /**
  * @interface OCIResourceItem
@@ -16,6 +16,17 @@ export interface OCIResourceItem {
   ocid: string;
 }
 
+/**
+ * @interface OCIVariableValueItem
+ * @description Represents a value of a template variable returned by a variable query.
+ * @property {string} text - The text of the value shown in the variable drop-down, e.g. a display name.
+ * @property {string} value - The value of the variable, e.g. an OCID.
+**/
+export interface OCIVariableValueItem {
+  text: string;
+  value: string;
+}
+
 /**
  * @interface OCIResourceGroupWithMetricNamesItem
  * @description Represents a resource group and its associated metric names.
@@ -59,6 +70,22 @@ export class ResponseParser {
     return tList;
   }
 
+  /**
+  * @function parseResources
+  * @description Parses the response from the OCI API calls listing compartments, log groups or logs.
+  * @param {any} results - The raw response from the OCI API.
+  * @returns {OCIResourceItem[]} An array of OCIResourceItem representing the resources, in the order of the backend.
+  */
+  parseResources(results: any): OCIResourceItem[] {
+    const resources: OCIResourceItem[] = [];
+    if (!results) {
+      return resources;
+    }
+
+    let rList: OCIResourceItem[] = JSON.parse(JSON.stringify(results));
+    return rList;
+  }
+
   /**
    * @function parseRegions
    * @description Parses the response from the OCI API call to list subscribed regions.
@@ -77,18 +104,18 @@ export class ResponseParser {
 
   /**
    * @function parseGetQuery
-   * @description Parses the `results` input and returns it as an array of strings.
-   * @param results - The input data, expected to be an array or JSON-serializable object.
-   * @returns An array of strings extracted from `results`. If `results` is null or undefined, returns an empty array.
+   * @description Parses the text and value pairs returned by a variable query.
+   * @param results - The input data, expected to be an array of text and value pairs, or of strings.
+   * @returns An array of text and value pairs. If `results` is null or undefined, returns an empty array.
   */
-  parseGetQuery(results: any): string[] {
-    const getquery: string[] = [];
+  parseGetQuery(results: any): OCIVariableValueItem[] {
+    const getquery: OCIVariableValueItem[] = [];
     if (!results) {
       return getquery;
     }
 
-    let rList: string[] = JSON.parse(JSON.stringify(results));
-    return rList;
+    let rList: any[] = JSON.parse(JSON.stringify(results));
+    return rList.map((item) => (isString(item) ? { text: item, value: item } : item));
   }
 
   /**
@@ -14,10 +14,19 @@ export enum DefaultOCIOptions {
 export const DEFAULT_TENANCY = "DEFAULT/";
 export const regionsQueryRegex = /^regions\(\s*(\".+\"|\'.+\'|\$\w+)\s*\)|^regions\(\)\s*/;
 export const tenanciesQueryRegex = /^tenancies\(\)\s*/;
+// compartments(tenancy), loggroups(tenancy, compartment, region) and logs(tenancy, loggroup, region) in multitenancy mode,
+// compartments(), loggroups(compartment, region) and logs(loggroup, region) in single tenancy mode; the compartment,
+// which may be an empty string, and the region are optional
+export const compartmentsQueryRegex = /^compartments\(\s*(\".+?\"|\'.+?\'|\$\w+)\s*\)|^compartments\(\)\s*/;
+export const logGroupsQueryRegex = /^loggroups\(\s*(\".*?\"|\'.*?\'|\$\w+)\s*(?:,\s*(\".*?\"|\'.*?\'|\$\w+)\s*)?(?:,\s*(\".+?\"|\'.+?\'|\$\w+)\s*)?\)|^loggroups\(\)\s*/;
+export const logsQueryRegex = /^logs\(\s*(\".+?\"|\'.+?\'|\$\w+)\s*(?:,\s*(\".+?\"|\'.+?\'|\$\w+)\s*)?(?:,\s*(\".+?\"|\'.+?\'|\$\w+)\s*)?\)/;
 // export const generalQueryRegex = /^search\(\s*(\".+\"|\'.+\'|\$\w+)\s*,\s*(\".+\"|\'.+\'|\$\w+)\s*(?:,\s*(\".+\"|\'.+\'|\$\w+)\s*)?\)/;
 // export const generalQueryRegex = /^search\(\s*(\".+\"|\'.+\'|\$\w+)\s*,\s*(\".+\"|\'.+\'|\$\w+)\s*,\s*(\".+\"|\'.+\'|\$\w+)\s*(?:,\s*(\".+\"|\'.+\'|\$\w+)\s*)?\)/;
 
-export const generalQueryRegex = /^search\(\s*(\".+\"|\'.+\'|\$\w+)\s*,\s*(\".+\"|\'.+\'|\$\w+)\s*(?:,\s*(\".+\"|\'.+\'|\$\w+))?\s*(?:,\s*(\".+\"|\'.+\'|\$\w+))?\)/;
+// search(tenancy, region, query, field, regex) in multitenancy mode and search(region, query, field, regex) in single tenancy mode,
+// the field is a path, e.g. "oracle.logid", or "<text path>, <value path>"; the field and the regex are optional.
+// Quoted arguments end at the first closing quote after which the rest of the call still parses, e.g. with quotes inside the query.
+export const generalQueryRegex = /^search\(\s*(\".+?\"|\'.+?\'|\$\w+)\s*,\s*(\".+?\"|\'.+?\'|\$\w+)\s*(?:,\s*(\".+?\"|\'.+?\'|\$\w+))?\s*(?:,\s*(\".+?\"|\'.+?\'|\$\w+))?\s*(?:,\s*(\".+?\"|\'.+?\'|\$\w+))?\)\s*$/;
 
 /**
  * Enum representing the different OCI resource API calls.
@@ -35,6 +44,34 @@ export enum OCIResourceCall {
   * Represents the API call to get log query.
   */
   getQuery = 'getquery',
+  /**
+  * Represents the API call to list the compartments of a tenancy.
+  */
+  Compartments = 'compartments',
+  /**
+  * Represents the API call to list the log groups of a compartment.
+  */
+  LogGroups = 'loggroups',
+  /**
+  * Represents the API call to list the logs of a log group.
+  */
+  Logs = 'logs',
+  /**
+  * Represents the API call to get the log records surrounding a log record.
+  */
+  LogContext = 'logcontext',
+  /**
+  * Represents the API call to get the path of the live tail channel of a query.
+  */
+  LiveTail = 'livetail',
+  /**
+  * Represents the API call to read again and test the credentials of the tenancies.
+  */
+  Revalidate = 'revalidate',
+  /**
+  * Represents the API call to probe the IAM permissions of a tenancy.
+  */
+  Diagnostics = 'diagnostics',
 }
 
 /**
@@ -67,6 +104,11 @@ export enum QueryPlaceholder {
  * - tenancymode: A string that indicates the mode of tenancy (e.g., "single" or "multi").
  * - regions (optional): An array or object that contains information about available regions in OCI.
  * - region (optional): A string representing a specific region in OCI.
+ * - fillMode (optional): How empty rounddown buckets of time series queries are filled ("zero", "null" or "previous").
+ * - logVolume (optional): Set for the log volume histogram query of a log records query in Explore.
+ * - timeout (optional): Timeout of the query in seconds, overrides the query timeout of the datasource.
+ * - sortDirection (optional): Time order of the log records of queries without a sort command ("asc" or "desc", the default).
+ * - annotation (optional): The mapping of log fields to annotation fields, used by annotation queries.
  */
 export interface OCIQuery extends DataQuery {
   searchQuery?: string;
@@ -76,92 +118,125 @@ export interface OCIQuery extends DataQuery {
   tenancymode: string;
   regions?: any;
   region?: string;
+  fillMode?: string;
+  logVolume?: boolean;
+  timeout?: number;
+  sortDirection?: string;
+  annotation?: OCIAnnotationMapping;
+}
+
+/**
+ * The OCIAnnotationMapping interface describes how log records are mapped to annotations.
+ * Field names are dotted paths within the log content, e.g. "time", "data.message" or "oracle.logid".
+ */
+export interface OCIAnnotationMapping {
+  timeField?: string;
+  timeEndField?: string;
+  titleField?: string;
+  textField?: string;
+  tagsField?: string;
+  limit?: number;
 }
 
+export const ANNOTATION_QUERY_TYPE = 'annotation';
+
 /**
 * These are options configured for each DataSource instance
 */
 export interface OCIDataSourceOptions extends DataSourceJsonData {
 	tenancyName: string; // name of the base tenancy
-	environment?: string; // oci-cli, oci-instance
+	environment?: string; // local, OCI Instance, OCI Resource Principal, OKE Workload Identity, OCI Session Token, OCI Config File
+	configFilePath?: string; // OCI config file on the Grafana server, for OCI Session Token and OCI Config File
+	configProfiles?: string[]; // profiles of the OCI config file exposed as tenancies, for OCI Config File
+	configProfile?: string; // profile of the OCI config file holding the session, for OCI Session Token
 	tenancymode?: string; // multi-profile, cross-tenancy-policy
-	xtenancy0: string;
-
-	addon1: boolean;
-	addon2: boolean;
-	addon3: boolean;
-	addon4: boolean;
-
-	customregionbool0: boolean;
-	customregionbool1: boolean;
-	customregionbool2: boolean;
-	customregionbool3: boolean;
-	customregionbool4: boolean;
-	customregionbool5: boolean;
-
-	customregion0: string
-	customregion1: string
-	customregion2: string
-	customregion3: string
-	customregion4: string	
-	customregion5: string 
-
-	profile0: string;
-	region0: string;
-
-	profile1: string;
-	region1: string;
-
-	profile2: string;
-	region2: string;
+	xtenancy0?: string; // legacy single cross tenancy, migrated to crossTenancies
+	crossTenancies?: OCICrossTenancy[]; // target tenancies read by instance principals through cross-tenancy policies
+
+	profiles?: OCIProfileSettings[]; // user principal profiles, secured settings are stored with the index suffix
+	endpoints?: OCIEndpointSettings; // endpoint overrides of the OCI services, for every environment
+	httpProxyUrl?: string; // HTTP(S) proxy of the OCI clients, the password is the httpProxyPassword secured setting
+	httpProxyUsername?: string;
+	enableSecureSocksProxy?: boolean; // Grafana secure socks proxy (private data source connect)
+	tlsMinVersion?: string; // 1.2 or 1.3, the CA bundle and client certificate are the tlsCACert, tlsClientCert and tlsClientKey secured settings
+	queryTimeout?: number; // timeout of the queries in seconds, overridden by the timeout of a query
+	partialResults?: boolean; // return the data collected before a failing page or interval, with a warning
+	searchChunkSize?: number; // minutes of the chunks searched in parallel by log records queries, -1 to disable
+
+	// Legacy numbered profile settings, migrated to profiles by the config editor
+	addon1?: boolean;
+	addon2?: boolean;
+	addon3?: boolean;
+	addon4?: boolean;
+
+	customregionbool0?: boolean;
+	customregionbool1?: boolean;
+	customregionbool2?: boolean;
+	customregionbool3?: boolean;
+	customregionbool4?: boolean;
+	customregionbool5?: boolean;
+
+	customregion0?: string;
+	customregion1?: string;
+	customregion2?: string;
+	customregion3?: string;
+	customregion4?: string;
+	customregion5?: string;
+
+	profile0?: string;
+	region0?: string;
+
+	profile1?: string;
+	region1?: string;
+
+	profile2?: string;
+	region2?: string;
+
+	profile3?: string;
+	region3?: string;
+
+	profile4?: string;
+	region4?: string;
+
+	profile5?: string;
+	region5?: string;
+}
 
-	profile3: string;
-	region3: string;
+/**
+ * A target tenancy read by instance principals through cross-tenancy policies, with its friendly name.
+ */
+export interface OCICrossTenancy {
+	name: string;
+	tenancy: string;
+}
 
-	profile4: string;
-	region4: string;
+/**
+ * Non secured settings of a user principal profile.
+ * The secured settings of the profile at index i of the profile list are stored with the i suffix,
+ * e.g. tenancy0, user0, fingerprint0, privkey0, privkeypass0 and customdomain0.
+ */
+export interface OCIProfileSettings {
+	name: string;
+	region?: string;
+	dedicated?: boolean;
+	customRegion?: string;
+	endpoints?: OCIEndpointSettings; // endpoint overrides of the profile, taking precedence over the datasource ones
+}
 
-	profile5: string;
-	region5: string;
+/**
+ * Explicit endpoints of the OCI services, overriding the endpoints resolved from the region and realm.
+ * An empty endpoint is resolved from the region.
+ */
+export interface OCIEndpointSettings {
+	loggingSearch?: string;
+	loggingManagement?: string;
+	identity?: string;
 }
 
 /**
  * Value that is used in the backend, but never sent over HTTP to the frontend
  */
 export interface OCISecureJsonData {
-	tenancy0: string;
-	user0: string;
-	privkey0: string;
-	fingerprint0: string;
-	customdomain0: string
-
-	tenancy1: string;
-	user1: string;
-	fingerprint1: string;
-	privkey1: string;
-	customdomain1: string
-
-	tenancy2: string;
-	user2: string;
-	fingerprint2: string;
-	privkey2: string;
-	customdomain2: string
-
-	tenancy3: string;
-	user3: string;
-	fingerprint3: string;
-	privkey3: string;
-	customdomain3: string
-
-	tenancy4: string;
-	user4: string;
-	fingerprint4: string;
-	privkey4: string;
-	customdomain4: string
-
-	tenancy5: string;
-	user5: string;
-	fingerprint5: string;
-	privkey5: string;
-	customdomain5: string
+	// tenancy<i>, user<i>, fingerprint<i>, privkey<i>, privkeypass<i> and customdomain<i> of the profile at index i
+	[key: string]: string;
 }